@@ -0,0 +1,140 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// the affix catalogue: the grammatical function and description of every affix fwew-lib finds
+//
+//go:embed affixes.json
+var affixCatalogueJSON []byte
+
+// affixInfo is one entry of the affix catalogue.
+// Forms are all spellings of the affix, e.g. "ti", "t" and "it" for the accusative.
+type affixInfo struct {
+	Type        string            `json:"type"` // prefix, infix, suffix or lenition
+	Forms       []string          `json:"forms"`
+	Function    string            `json:"function"`    // Leipzig-style gloss, e.g. "ACC"
+	Description map[string]string `json:"description"` // by language code; "en" is always there
+}
+
+// affixEntry is the dictionary entry of an affix.
+type affixEntry struct {
+	ID   string `json:"id"`
+	Navi string `json:"navi"`
+	Link string `json:"link"`
+}
+
+// affixExplanation is what one affix of a search result does.
+type affixExplanation struct {
+	Affix       string      `json:"affix"`
+	Type        string      `json:"type"`
+	Function    string      `json:"function,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Lang        string      `json:"lang,omitempty"` // language of Description, "en" if lang has no translation
+	Entry       *affixEntry `json:"entry,omitempty"`
+}
+
+var (
+	// the affix catalogue by type and form, e.g. "suffix:ti"
+	affixCatalogue map[string]affixInfo
+	// dictionary entries of affixes by type and form, rebuilt with every
+	// dictionary load (see dictionaryLoaded)
+	affixEntries map[string]affixEntry
+)
+
+func init() {
+	var infos []affixInfo
+	if err := json.Unmarshal(affixCatalogueJSON, &infos); err != nil {
+		log.Fatalf("affix catalogue: %v", err)
+	}
+	affixCatalogue = map[string]affixInfo{}
+	for _, info := range infos {
+		for _, form := range info.Forms {
+			affixCatalogue[info.Type+":"+form] = info
+		}
+	}
+}
+
+// rebuild affixEntries from the current dictionary.
+// Affixes are entered as "tì-", "<ìrm>" or "-ti".
+func buildAffixEntries() {
+	words, err := fwew.List(nil, 0)
+	if err != nil {
+		log.Printf("affix entries: %v", err)
+		return
+	}
+
+	entries := map[string]affixEntry{}
+	for _, word := range words {
+		navi := strings.ToLower(strings.TrimSpace(word.Navi))
+		var key string
+		switch {
+		case strings.HasPrefix(navi, "<") && strings.HasSuffix(navi, ">"):
+			key = "infix:" + strings.Trim(navi, "<>")
+		case strings.HasPrefix(navi, "-") && !strings.HasSuffix(navi, "-"):
+			key = "suffix:" + strings.TrimPrefix(navi, "-")
+		case strings.HasSuffix(navi, "-") && !strings.HasPrefix(navi, "-"):
+			key = "prefix:" + strings.TrimSuffix(navi, "-")
+		default:
+			continue
+		}
+		if _, ok := entries[key]; !ok {
+			entries[key] = affixEntry{ID: word.ID, Navi: word.Navi, Link: config.WebRoot + "/fwew-simple/true/" + url.PathEscape(word.Navi)}
+		}
+	}
+	affixEntries = entries
+}
+
+// explain the affixes of word, with descriptions in lang.
+// Entries without a translation to lang get English.
+func explainAffixes(word fwew.Word, lang string) []affixExplanation {
+	explanations := []affixExplanation{}
+	add := func(kind string, affixes []string) {
+		for _, affix := range affixes {
+			e := affixExplanation{Affix: affix, Type: kind}
+			key := kind + ":" + strings.ToLower(affix)
+			if info, ok := affixCatalogue[key]; ok {
+				e.Function = info.Function
+				e.Description, e.Lang = info.Description[lang], lang
+				if e.Description == "" {
+					e.Description, e.Lang = info.Description["en"], "en"
+				}
+			}
+			if entry, ok := affixEntries[key]; ok {
+				e.Entry = &entry
+			}
+			explanations = append(explanations, e)
+		}
+	}
+	add("prefix", word.Affixes.Prefix)
+	add("infix", word.Affixes.Infix)
+	add("suffix", word.Affixes.Suffix)
+	add("lenition", word.Affixes.Lenition)
+	return explanations
+}
+
+// the text of an explanation, e.g. "-ti ACC: accusative case: ..."
+func (e affixExplanation) String() string {
+	var affix string
+	switch e.Type {
+	case "prefix":
+		affix = e.Affix + "-"
+	case "infix":
+		affix = "<" + e.Affix + ">"
+	case "suffix":
+		affix = "-" + e.Affix
+	default:
+		affix = e.Affix
+	}
+	if e.Function == "" {
+		return affix
+	}
+	return affix + " " + e.Function + ": " + e.Description
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// every entry of the catalogue is described in every language of the dictionary
+func TestAffixCatalogueTranslated(t *testing.T) {
+	for key, info := range affixCatalogue {
+		if info.Function == "" {
+			t.Errorf("%s has no function", key)
+		}
+		for _, lang := range languages {
+			if info.Description[lang] == "" {
+				t.Errorf("%s has no %s description", key, lang)
+			}
+		}
+	}
+}
+
+func TestExplainAffixes(t *testing.T) {
+	var word fwew.Word
+	word.Affixes.Prefix = []string{"ay"}
+	word.Affixes.Infix = []string{"ìrm"}
+	word.Affixes.Suffix = []string{"It", "zzz"}
+	word.Affixes.Lenition = []string{"t→s"}
+
+	tests := []struct {
+		affix, kind, function string
+	}{
+		{"ay", "prefix", "PL"},
+		{"ìrm", "infix", "PST.REC.IPFV"},
+		{"It", "suffix", "ACC"}, // any spelling, any case
+		{"zzz", "suffix", ""},   // not in the catalogue
+		{"t→s", "lenition", "LEN"},
+	}
+	got := explainAffixes(word, "de")
+	if len(got) != len(tests) {
+		t.Fatalf("explainAffixes = %+v, want %d explanations", got, len(tests))
+	}
+	for i, tt := range tests {
+		e := got[i]
+		if e.Affix != tt.affix || e.Type != tt.kind || e.Function != tt.function {
+			t.Errorf("explanation %d = %s %s %s, want %s %s %s", i, e.Type, e.Affix, e.Function, tt.kind, tt.affix, tt.function)
+		}
+		if tt.function != "" && (e.Lang != "de" || e.Description != affixCatalogue[tt.kind+":"+strings.ToLower(tt.affix)].Description["de"]) {
+			t.Errorf("explanation %d: %s description %q, want the German one", i, e.Lang, e.Description)
+		}
+		if tt.function == "" && (e.Description != "" || e.Lang != "") {
+			t.Errorf("explanation %d: %s description %q for an unknown affix", i, e.Lang, e.Description)
+		}
+	}
+}
+
+// a language an entry has no translation for gets English
+func TestExplainAffixesFallback(t *testing.T) {
+	saved := affixCatalogue
+	defer func() { affixCatalogue = saved }()
+	affixCatalogue = map[string]affixInfo{
+		"suffix:ti": {Type: "suffix", Forms: []string{"ti"}, Function: "ACC",
+			Description: map[string]string{"en": "accusative", "de": "Akkusativ"}},
+	}
+
+	var word fwew.Word
+	word.Affixes.Suffix = []string{"ti"}
+	for lang, want := range map[string][2]string{
+		"de": {"Akkusativ", "de"},
+		"fr": {"accusative", "en"},
+		"":   {"accusative", "en"},
+	} {
+		e := explainAffixes(word, lang)[0]
+		if e.Description != want[0] || e.Lang != want[1] {
+			t.Errorf("explainAffixes(%q) = %q in %q, want %q in %q", lang, e.Description, e.Lang, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"net/http"
+	"reflect"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// Content-Type of Anki exports
+const ankiContentType = "text/plain; charset=utf-8"
+
+// Export the words of /list/{args} as Anki notes, see writeAnki
+func ankiList(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uncommadArgs := strings.ReplaceAll(vars["args"], ", ", ",")
+	args := strings.Split(uncommadArgs, " ")
+
+	words, err := fwew.List(args, uint8(0))
+	if err != nil || len(words) == 0 {
+		writeNoResults(w, r)
+		return
+	}
+
+	writeAnki(w, r, words, vars["lang"], "")
+}
+
+// Export the words of /random2/{n}/{c}/{args} as Anki notes, see writeAnki
+func ankiRandom(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ints, e := intVars(vars, "n")
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	args := strings.Split(vars["args"], " ")
+	seed, ok := requestSeed(w, r)
+	if !ok {
+		return
+	}
+	words, err := randomWords(seed, ints[0], args, digraphMode(vars["c"]))
+	if err != nil || len(words) == 0 {
+		writeNoResults(w, r)
+		return
+	}
+
+	writeAnki(w, r, words, vars["lang"], fmt.Sprintf("random words of seed %d", seed))
+}
+
+// Write words in Anki's text import format, one Basic note per word:
+// the Na'vi word with its stressed syllables on the front, and the definition
+// in lang, part of speech and IPA on the back.
+// Notes get a GUID made of the word ID and lang, so importing an updated
+// export again updates the existing notes instead of adding new ones.
+// A comment, if given, goes on a # line after the headers, which Anki skips.
+func writeAnki(w http.ResponseWriter, r *http.Request, words []fwew.Word, lang string, comment string) {
+	if e := checkLang(lang); e != nil {
+		writeError(w, e)
+		return
+	}
+
+	deck := r.URL.Query().Get("deck")
+	if deck == "" {
+		deck = "Na'vi"
+	}
+	// header values end at the line
+	deck = strings.Join(strings.Fields(deck), " ")
+
+	var b bytes.Buffer
+	b.WriteString("#separator:tab\n")
+	b.WriteString("#html:true\n")
+	b.WriteString("#notetype:Basic\n")
+	fmt.Fprintf(&b, "#deck:%s\n", deck)
+	b.WriteString("#guid column:1\n")
+	b.WriteString("#tags column:4\n")
+	b.WriteString("#columns:GUID\tFront\tBack\tTags\n")
+	if comment != "" {
+		fmt.Fprintf(&b, "# %s\n", comment)
+	}
+
+	cw := csv.NewWriter(&b)
+	cw.Comma = '\t'
+	for _, word := range words {
+		front := html.EscapeString(word.Navi)
+		if syllables := stressedSyllables(word); syllables != "" {
+			front += "<br><small>" + syllables + "</small>"
+		}
+		back := html.EscapeString(localDefinition(word, lang)) +
+			"<br><i>" + html.EscapeString(word.PartOfSpeech) + "</i>" +
+			"<br>[" + html.EscapeString(word.IPA) + "]"
+		cw.Write([]string{"fwew-" + lang + "-" + word.ID, front, back, ankiTags(word)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
+
+	setResultCount(r, len(words))
+	w.Header().Set("Content-Type", ankiContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="fwew-`+lang+`.txt"`)
+	w.Write(b.Bytes())
+}
+
+// the definition of word in lang, falling back to English where there is no translation
+func localDefinition(word fwew.Word, lang string) string {
+	definition := reflect.ValueOf(word).FieldByName(strings.ToUpper(lang)).String()
+	if definition == "" || definition == "NULL" {
+		return word.EN
+	}
+	return definition
+}
+
+// The syllables of word with the stressed ones underlined, e.g. "<u>ta</u>-ron".
+// Like fwew-lib, this reads the stress marks of the IPA.
+func stressedSyllables(word fwew.Word) string {
+	if !strings.Contains(word.Syllables, "-") {
+		return ""
+	}
+
+	var stressed []bool
+	for _, ipa := range strings.Split(word.IPA, " ") {
+		if ipa == "or" {
+			break
+		}
+		for _, syllable := range strings.Split(ipa, ".") {
+			stressed = append(stressed, strings.Contains(syllable, "ˈ"))
+		}
+	}
+
+	i := 0
+	parts := strings.Split(word.Syllables, " ")
+	for j, part := range parts {
+		syllables := strings.Split(part, "-")
+		for k, syllable := range syllables {
+			syllable = html.EscapeString(syllable)
+			if i < len(stressed) && stressed[i] {
+				syllable = "<u>" + syllable + "</u>"
+			}
+			syllables[k] = syllable
+			i++
+		}
+		parts[j] = strings.Join(syllables, "-")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Anki tags of word: fwew and its part of speech, e.g. "fwew pos::vtr."
+func ankiTags(word fwew.Word) string {
+	tags := []string{"fwew"}
+	for _, pos := range strings.Split(word.PartOfSpeech, ",") {
+		if pos = strings.Join(strings.Fields(pos), "_"); pos != "" {
+			tags = append(tags, "pos::"+pos)
+		}
+	}
+	return strings.Join(tags, " ")
+}
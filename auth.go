@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maximum age of a signed admin request
+const signatureMaxAge = 5 * time.Minute
+
+// maximum size of a signed admin request body
+const signatureMaxBody = 1 << 20
+
+// whether any admin credential has been configured
+func adminConfigured() bool {
+	return config.AdminToken != "" || config.AdminSecret != ""
+}
+
+// Check the admin credentials of a request. Either of the following is accepted:
+//
+//	Authorization: Bearer <AdminToken>
+//
+// or a request signed with AdminSecret:
+//
+//	X-Fwew-Timestamp: <unix seconds>
+//	X-Fwew-Signature: hex(HMAC-SHA256(AdminSecret, timestamp + "\n" + method + "\n" + request URI + "\n" + body))
+func authorizeAdmin(r *http.Request) bool {
+	if config.AdminToken != "" {
+		auth := r.Header.Get("Authorization")
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if config.AdminSecret != "" && r.Header.Get("X-Fwew-Signature") != "" {
+		return verifySignature(r)
+	}
+
+	return false
+}
+
+// verify an HMAC-signed request, restoring the body for the handler
+func verifySignature(r *http.Request) bool {
+	timestamp := r.Header.Get("X-Fwew-Timestamp")
+	sent, err := hex.DecodeString(r.Header.Get("X-Fwew-Signature"))
+	if err != nil {
+		return false
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > signatureMaxAge || age < -signatureMaxAge {
+		return false
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, signatureMaxBody))
+		if err != nil {
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.AdminSecret))
+	mac.Write([]byte(timestamp + "\n" + r.Method + "\n" + r.URL.RequestURI() + "\n"))
+	mac.Write(body)
+	return hmac.Equal(sent, mac.Sum(nil))
+}
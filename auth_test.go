@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// sign a request as an admin client would
+func sign(r *http.Request, secret string, at time.Time, body string) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + r.Method + "\n" + r.URL.RequestURI() + "\n" + body))
+	r.Header.Set("X-Fwew-Timestamp", timestamp)
+	r.Header.Set("X-Fwew-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+	config.AdminToken = "token"
+	config.AdminSecret = "secret"
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		prepare func(r *http.Request)
+		ok      bool
+	}{
+		{"missing token", func(r *http.Request) {}, false},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tokem") }, false},
+		{"not bearer", func(r *http.Request) { r.Header.Set("Authorization", "Basic token") }, false},
+		{"valid bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, true},
+		{"valid signature", func(r *http.Request) { sign(r, "secret", now, "body") }, true},
+		{"wrong secret", func(r *http.Request) { sign(r, "secreT", now, "body") }, false},
+		{"other body", func(r *http.Request) { sign(r, "secret", now, "bodies") }, false},
+		{"stale timestamp", func(r *http.Request) { sign(r, "secret", now.Add(-signatureMaxAge-time.Minute), "body") }, false},
+		{"future timestamp", func(r *http.Request) { sign(r, "secret", now.Add(signatureMaxAge+time.Minute), "body") }, false},
+		{"signature not hex", func(r *http.Request) {
+			sign(r, "secret", now, "body")
+			r.Header.Set("X-Fwew-Signature", "xyz")
+		}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/update?x=1", strings.NewReader("body"))
+		tt.prepare(r)
+		if ok := authorizeAdmin(r); ok != tt.ok {
+			t.Errorf("%s: authorizeAdmin = %v, want %v", tt.name, ok, tt.ok)
+		}
+		// the body is still there for the handler
+		if body, _ := io.ReadAll(r.Body); string(body) != "body" {
+			t.Errorf("%s: body %q left for the handler", tt.name, body)
+		}
+	}
+}
+
+// only the credentials that are configured are accepted
+func TestAuthorizeAdminUnconfigured(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+	config.AdminToken = ""
+	config.AdminSecret = "secret"
+
+	r := httptest.NewRequest(http.MethodPost, "/api/update", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	if authorizeAdmin(r) {
+		t.Error("empty bearer token accepted while no AdminToken is set")
+	}
+
+	config.AdminSecret = ""
+	sign(r, "", time.Now(), "")
+	if authorizeAdmin(r) {
+		t.Error("signature accepted while no AdminSecret is set")
+	}
+}
+
+// update refuses requests before downloading anything
+func TestUpdateRefused(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	tests := []struct {
+		name        string
+		method      string
+		token       string
+		adminToken  string
+		status      int
+		allow, auth string
+	}{
+		{"non-POST", http.MethodGet, "token", "token", http.StatusMethodNotAllowed, "POST", ""},
+		{"disabled", http.MethodPost, "token", "", http.StatusForbidden, "", ""},
+		{"missing token", http.MethodPost, "", "token", http.StatusUnauthorized, "", `Bearer realm="fwew-api"`},
+		{"wrong token", http.MethodPost, "tokem", "token", http.StatusUnauthorized, "", `Bearer realm="fwew-api"`},
+	}
+	for _, tt := range tests {
+		config.AdminToken = tt.adminToken
+		config.AdminSecret = ""
+		r := httptest.NewRequest(tt.method, "/api/update", nil)
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		update(w, r)
+		if w.Code != tt.status || w.Header().Get("Allow") != tt.allow || w.Header().Get("WWW-Authenticate") != tt.auth {
+			t.Errorf("%s: status %d, Allow %q, WWW-Authenticate %q, want %d, %q, %q", tt.name,
+				w.Code, w.Header().Get("Allow"), w.Header().Get("WWW-Authenticate"), tt.status, tt.allow, tt.auth)
+		}
+	}
+}
+
+// a downloaded dictionary that cannot be loaded is replaced by the old one again
+func TestSwapDictionaryRestores(t *testing.T) {
+	file := fwew.FindDictionaryFile()
+	old, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.WriteFile(file, old, 0o644)
+		loadDictionary()
+		dictionaryLoaded()
+	}()
+	words := fwew.GetDictSizeSimple()
+
+	downloaded := file + ".new"
+	if err := os.WriteFile(downloaded, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := swapDictionary(file, downloaded); err == nil {
+		t.Fatal("empty dictionary loaded")
+	}
+	if now, _ := os.ReadFile(file); string(now) != string(old) {
+		t.Error("old dictionary file not put back")
+	}
+	if n := fwew.GetDictSizeSimple(); n != words {
+		t.Errorf("%d words loaded after restoring, want %d", n, words)
+	}
+	if e, _ := dictLoadError.Load().(string); e != "" {
+		t.Errorf("restored dictionary reported as failed: %s", e)
+	}
+	for _, leftover := range []string{downloaded, file + ".old"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s left behind", leftover)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+	"golang.org/x/text/unicode/norm"
+)
+
+// completion is one autocomplete result.
+type completion struct {
+	Text string `json:"text"` // the completed headword or gloss
+	Navi string `json:"navi"`
+	ID   string `json:"id"`
+	From string `json:"from"` // "navi" for headwords, else the language code of the gloss
+	key  string
+}
+
+// trieNode is one letter of the autocomplete tries.
+type trieNode struct {
+	children map[rune]*trieNode
+	items    []completion // entries whose key ends here
+}
+
+// trie maps normalized keys to completions.
+type trie struct {
+	root trieNode
+}
+
+// tries over Na'vi headwords ("navi") and the glosses of each language,
+// rebuilt with every dictionary load (see dictionaryLoaded)
+var autocompleteTries map[string]*trie
+
+func (t *trie) insert(item completion) {
+	node := &t.root
+	key := item.key
+	for _, c := range key {
+		if node.children == nil {
+			node.children = map[rune]*trieNode{}
+		}
+		child, ok := node.children[c]
+		if !ok {
+			child = &trieNode{}
+			node.children[c] = child
+		}
+		node = child
+	}
+	if !slices.Contains(node.items, item) {
+		node.items = append(node.items, item)
+	}
+}
+
+// Up to limit completions of prefix, shortest first, then alphabetical.
+// The trie is walked breadth first below the prefix, so only as much of it is
+// visited as needed to fill the limit.
+func (t *trie) complete(prefix string, limit int) []completion {
+	node := &t.root
+	for _, c := range prefix {
+		node = node.children[c]
+		if node == nil {
+			return nil
+		}
+	}
+
+	var found []completion
+	level := []*trieNode{node}
+	for len(level) > 0 && len(found) < limit {
+		var items []completion
+		var next []*trieNode
+		for _, n := range level {
+			items = append(items, n.items...)
+			for _, child := range n.children {
+				next = append(next, child)
+			}
+		}
+		slices.SortFunc(items, compareCompletions)
+		found = append(found, items[:min(len(items), limit-len(found))]...)
+		level = next
+	}
+	return found
+}
+
+// order completions by length, then alphabetically, headwords first
+func compareCompletions(a, b completion) int {
+	if d := utf8.RuneCountInString(a.key) - utf8.RuneCountInString(b.key); d != 0 {
+		return d
+	}
+	if c := strings.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	if a.From != b.From {
+		if a.From == "navi" {
+			return -1
+		}
+		if b.From == "navi" {
+			return 1
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// The trie key of s: lower case, without apostrophes or diacritics, so that
+// "'awkx", "awkx" and "äwkx" all match
+func autocompleteKey(s string) string {
+	var b strings.Builder
+	for _, c := range norm.NFD.String(strings.ToLower(strings.TrimSpace(s))) {
+		if unicode.Is(unicode.Mn, c) || c == '\'' || c == '’' || c == '‘' {
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// the separate glosses of a definition, e.g. "I, me" -> "I", "me"
+func glosses(definition string) []string {
+	var found []string
+	for _, g := range strings.FieldsFunc(definition, func(r rune) bool { return r == ',' || r == ';' }) {
+		if g = strings.TrimSpace(g); g != "" && g != "NULL" {
+			found = append(found, g)
+		}
+	}
+	return found
+}
+
+// rebuild autocompleteTries from the current dictionary
+func buildAutocompleteTries() {
+	words, err := fwew.List(nil, 0)
+	if err != nil {
+		return
+	}
+
+	tries := map[string]*trie{"navi": {}}
+	for _, lang := range languages {
+		tries[lang] = &trie{}
+	}
+	for _, word := range words {
+		tries["navi"].insert(completion{Text: word.Navi, Navi: word.Navi, ID: word.ID, From: "navi", key: autocompleteKey(word.Navi)})
+		v := reflect.ValueOf(word)
+		for _, lang := range languages {
+			for _, g := range glosses(v.FieldByName(strings.ToUpper(lang)).String()) {
+				tries[lang].insert(completion{Text: g, Navi: word.Navi, ID: word.ID, From: lang, key: autocompleteKey(g)})
+			}
+		}
+	}
+	autocompleteTries = tries
+}
+
+// Complete a Na'vi headword or a gloss in lang:
+//
+//	limit  number of completions (default 10, at most 50)
+func getAutocomplete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	lang := vars["lang"]
+	if e := checkLang(lang); e != nil {
+		writeError(w, e)
+		return
+	}
+
+	limit, e := nonNegativeParam(r.URL.Query(), "limit")
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	if limit == 0 {
+		limit = 10
+	}
+	limit = min(limit, 50)
+
+	prefix := autocompleteKey(vars["prefix"])
+	var found []completion
+	if prefix != "" && autocompleteTries != nil {
+		found = append(autocompleteTries["navi"].complete(prefix, limit), autocompleteTries[lang].complete(prefix, limit)...)
+		slices.SortStableFunc(found, compareCompletions)
+		found = found[:min(len(found), limit)]
+	}
+
+	setResultCount(r, len(found))
+	if found == nil {
+		found = []completion{}
+	}
+	render(w, r, found)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAutocompleteKey(t *testing.T) {
+	tests := map[string]string{
+		"'awkx":      "awkx",
+		"’Äwkx":      "awkx",
+		"a\u0308wkx": "awkx", // decomposed ä
+		" Tìkangkem": "tikangkem",
+		"hand-made":  "hand-made",
+	}
+	for s, want := range tests {
+		if got := autocompleteKey(s); got != want {
+			t.Errorf("autocompleteKey(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestGlosses(t *testing.T) {
+	tests := map[string][]string{
+		"I, me":             {"I", "me"},
+		"hunt; go hunting":  {"hunt", "go hunting"},
+		"NULL":              nil,
+		" , ":               nil,
+		"banshee, mountain": {"banshee", "mountain"},
+	}
+	for definition, want := range tests {
+		if got := glosses(definition); !slices.Equal(got, want) {
+			t.Errorf("glosses(%q) = %q, want %q", definition, got, want)
+		}
+	}
+}
+
+func TestTrieComplete(t *testing.T) {
+	var tr trie
+	for _, c := range []completion{
+		{Text: "tsmukan", ID: "1", From: "navi"},
+		{Text: "tute", ID: "2", From: "navi"},
+		{Text: "tsmuke", ID: "3", From: "navi"},
+		{Text: "tìtxen", ID: "4", From: "navi"},
+		{Text: "taron", ID: "5", From: "navi"},
+		{Text: "taron", ID: "6", From: "de"},
+		{Text: "'ampi", ID: "7", From: "navi"},
+	} {
+		c.Navi = c.Text
+		c.key = autocompleteKey(c.Text)
+		tr.insert(c)
+		tr.insert(c) // inserting twice keeps one
+	}
+
+	ids := func(found []completion) []string {
+		var ids []string
+		for _, c := range found {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		prefix string
+		limit  int
+		ids    []string
+	}{
+		{"t", 10, []string{"2", "5", "6", "4", "3", "1"}}, // shortest first, then alphabetical, headwords first
+		{"t", 2, []string{"2", "5"}},
+		{"ts", 10, []string{"3", "1"}},
+		{"tit", 10, []string{"4"}}, // ì is found by i
+		{"amp", 10, []string{"7"}}, // and 'ampi without the apostrophe
+		{"x", 10, nil},
+	}
+	for _, tt := range tests {
+		if got := ids(tr.complete(autocompleteKey(tt.prefix), tt.limit)); !slices.Equal(got, tt.ids) {
+			t.Errorf("complete(%q, %d) = %q, want %q", tt.prefix, tt.limit, got, tt.ids)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// maximum size of a batch request body
+const batchMaxBody = 1 << 20
+
+// batchQuery is one item of a batch search.
+// Affixes defaults to true, Strict and Reef default to false,
+// matching /api/fwew/{nav}.
+type batchQuery struct {
+	Query   string `json:"query"`
+	Affixes *bool  `json:"affixes"`
+	Strict  bool   `json:"strict"`
+	Reef    bool   `json:"reef"`
+}
+
+// batchResult is the outcome of one item of a batch search.
+type batchResult struct {
+	Results [][]fwew.Word `json:"results,omitempty"`
+	Error   *apiError     `json:"error,omitempty"`
+}
+
+// Search many Na'vi inputs at once.
+// Returns an object keyed by query; a failing query gets an error instead of results.
+// Repeated queries are only searched once; a query repeated with different options
+// gets an invalid_combination error instead of results.
+func batchSearchWord(w http.ResponseWriter, r *http.Request) {
+	var queries []batchQuery
+
+	r.Body = http.MaxBytesReader(w, r.Body, batchMaxBody)
+	err := json.NewDecoder(r.Body).Decode(&queries)
+	if err != nil {
+		writeError(w, errInvalidBody("invalid batch: expected a JSON array of queries"))
+		return
+	}
+
+	if len(queries) > config.BatchMaxSize {
+		writeError(w, errTooLarge(fmt.Sprintf("batch too large: at most %d queries allowed", config.BatchMaxSize)))
+		return
+	}
+
+	var unique []batchQuery
+	seen := map[string]translateOptions{}
+	conflicting := map[string]bool{}
+	for _, q := range queries {
+		opts := q.options()
+		if first, ok := seen[q.Query]; ok {
+			if first != opts {
+				conflicting[q.Query] = true
+			}
+			continue
+		}
+		seen[q.Query] = opts
+		unique = append(unique, q)
+	}
+
+	results := make(map[string]batchResult, len(unique))
+	for query := range conflicting {
+		results[query] = batchResult{Error: errInvalidCombination("query", fmt.Sprintf("query %q is repeated with different options", query))}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.BatchConcurrency)
+
+	for _, q := range unique {
+		if conflicting[q.Query] {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(q batchQuery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res := searchBatchQuery(q)
+
+			mu.Lock()
+			results[q.Query] = res
+			mu.Unlock()
+		}(q)
+	}
+	wg.Wait()
+
+	setResultCount(r, len(results))
+	render(w, r, results)
+}
+
+// the search options of a batch item, with its defaults filled in
+func (q batchQuery) options() translateOptions {
+	affixes := true
+	if q.Affixes != nil {
+		affixes = *q.Affixes
+	}
+	return translateOptions{Query: q.Query, Affixes: affixes, Strict: q.Strict, Reef: q.Reef}
+}
+
+// search a single item of a batch
+func searchBatchQuery(q batchQuery) batchResult {
+	if q.Query == "" {
+		return batchResult{Error: errMissingParam("query")}
+	}
+
+	words, err := translate(q.options())
+	if err != nil || len(words) == 0 {
+		return batchResult{Error: errNoResults()}
+	}
+	if !foundAny(words) {
+		return batchResult{Error: errNoResultsSuggest(words)}
+	}
+
+	return batchResult{Results: words}
+}
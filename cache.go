@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// response headers kept with a cached response
+var cachedHeaders = []string{"Content-Type", "Content-Disposition", "X-Total-Count", "Link"}
+
+// global response cache, sized in loadConfig
+var responseCache *lruCache
+
+// time the current dictionary was loaded, used as Last-Modified
+var dictLoaded = time.Now()
+
+// cachedResponse is a complete response of a deterministic endpoint.
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	results int
+}
+
+func (c *cachedResponse) size() int64 {
+	return int64(len(c.key) + len(c.body))
+}
+
+// lruCache keeps the most recently used responses up to a total size in bytes.
+type lruCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List // front is most recently used
+	items    map[string]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+func newLRUCache(maxBytes int64) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Get a cached response
+func (c *lruCache) Get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedResponse), true
+}
+
+// Add a response, evicting the least recently used ones to make room.
+// Responses larger than the whole cache are not stored.
+func (c *lruCache) Add(resp *cachedResponse) {
+	if resp.size() > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[resp.key]; ok {
+		c.size -= e.Value.(*cachedResponse).size()
+		c.order.Remove(e)
+	}
+	c.items[resp.key] = c.order.PushFront(resp)
+	c.size += resp.size()
+
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		old := oldest.Value.(*cachedResponse)
+		c.order.Remove(oldest)
+		delete(c.items, old.key)
+		c.size -= old.size()
+	}
+}
+
+// Clear removes every cached response
+func (c *lruCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = map[string]*list.Element{}
+	c.size = 0
+}
+
+// Stats returns the number of cache hits and misses so far
+func (c *lruCache) Stats() (hits uint64, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// responseRecorder buffers a response so it can be cached before it is sent.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// ETag of the deterministic response cached under key: it changes with the URL,
+// the Accept header, the dictionary build and the API version
+func responseETag(key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return fmt.Sprintf(`"%s-%s-%x"`, version.DictBuild, version.APIVersion, h.Sum64())
+}
+
+// whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// whether r already has the response with etag, last modified at modified:
+// If-None-Match decides if given, else If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// Wrap a deterministic handler: its responses only depend on the request and the dictionary.
+// Successful responses are served from the cache and carry validators derived from the
+// request and the dictionary build; revalidation with If-None-Match or If-Modified-Since
+// is answered with 304. Errors are neither cached nor validated, so they are always sent.
+func cacheable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		key := r.URL.RequestURI() + "\n" + r.Header.Get("Accept")
+		var resp *cachedResponse
+		ok := false
+		if responseCache != nil {
+			resp, ok = responseCache.Get(key)
+		}
+		header := http.Header{}
+		if ok {
+			header = resp.header
+		} else {
+			rec := &responseRecorder{header: header}
+			next(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			resp = &cachedResponse{key: key, status: rec.status, header: http.Header{}, body: rec.body.Bytes(), results: infoOf(r).results}
+			for _, h := range cachedHeaders {
+				if v := rec.header.Values(h); len(v) > 0 {
+					resp.header[h] = v
+				}
+			}
+			if resp.status == http.StatusOK && responseCache != nil {
+				responseCache.Add(resp)
+			}
+		}
+
+		setResultCount(r, resp.results)
+		for h, v := range header {
+			w.Header()[h] = v
+		}
+		addVary(w.Header(), "Accept")
+		if resp.status == http.StatusOK {
+			etag := responseETag(key)
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", dictLoaded.UTC().Format(http.TimeFormat))
+			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(config.CacheMaxAge))
+			if notModified(r, etag, dictLoaded) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(resp.status)
+		w.Write(resp.body)
+	}
+}
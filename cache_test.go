@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// a cacheable handler that answers with the number of times it ran,
+// and a fresh cache for it
+func countingHandler(t *testing.T) (http.HandlerFunc, *int) {
+	saved := responseCache
+	t.Cleanup(func() { responseCache = saved })
+	responseCache = newLRUCache(1 << 20)
+
+	calls := 0
+	return cacheable(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "%s %d", r.Header.Get("Accept"), calls)
+	}), &calls
+}
+
+func getCached(h http.HandlerFunc, accept, ifNoneMatch string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/fwew/tute", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestCacheableNotModified(t *testing.T) {
+	h, calls := countingHandler(t)
+
+	first := getCached(h, "", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first response %d with ETag %q", first.Code, etag)
+	}
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := getCached(h, "", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: %d %q with ETag %q, want 304 without a body", inm, w.Code, w.Body, w.Header().Get("ETag"))
+		}
+	}
+	if w := getCached(h, "", `"other"`); w.Code != http.StatusOK || w.Body.String() != first.Body.String() {
+		t.Errorf("If-None-Match of another response: %d %q, want 200 %q", w.Code, w.Body, first.Body)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want once", *calls)
+	}
+}
+
+func TestCacheableVariesWithAccept(t *testing.T) {
+	h, calls := countingHandler(t)
+
+	asJSON := getCached(h, "application/json", "")
+	asCSV := getCached(h, "text/csv", "")
+	if asJSON.Body.String() == asCSV.Body.String() || asJSON.Header().Get("ETag") == asCSV.Header().Get("ETag") {
+		t.Errorf("same response for both Accept headers: %q %s, %q %s",
+			asJSON.Body, asJSON.Header().Get("ETag"), asCSV.Body, asCSV.Header().Get("ETag"))
+	}
+	if vary := asCSV.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Accept" {
+		t.Errorf("Vary %q, want Accept", vary)
+	}
+	if w := getCached(h, "application/json", asJSON.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("revalidating the JSON response: %d, want 304", w.Code)
+	}
+	if w := getCached(h, "text/csv", asJSON.Header().Get("ETag")); w.Code != http.StatusOK || w.Body.String() != asCSV.Body.String() {
+		t.Errorf("CSV with the ETag of the JSON response: %d %q, want 200 %q", w.Code, w.Body, asCSV.Body)
+	}
+	if *calls != 2 {
+		t.Errorf("handler ran %d times, want twice", *calls)
+	}
+}
+
+// a new dictionary empties the cache and changes the ETags
+func TestCacheableReload(t *testing.T) {
+	h, calls := countingHandler(t)
+	file := fwew.FindDictionaryFile()
+	old, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.WriteFile(file, old, 0o644)
+		dictionaryLoaded()
+	}()
+
+	before := getCached(h, "", "")
+	if err := os.WriteFile(file, append(old, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dictionaryLoaded()
+
+	w := getCached(h, "", before.Header().Get("ETag"))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == before.Header().Get("ETag") {
+		t.Errorf("revalidating after a reload: %d with ETag %s, want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+	if *calls != 2 {
+		t.Errorf("handler ran %d times, want again after the reload", *calls)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// the longest expression /api/calc evaluates
+const calcMaxLength = 500
+
+// operators and how they are written in worked solutions
+var calcOperators = map[string]string{"+": "+", "-": "−", "*": "×", "×": "×", "/": "÷", "÷": "÷"}
+
+// calculation is an evaluated expression of Na'vi numbers with its worked solution.
+type calculation struct {
+	Expression string     `json:"expression"`
+	Result     number     `json:"result"`
+	Steps      []calcStep `json:"steps"` // in the order they are worked out
+}
+
+// calcStep is one operation of a calculation.
+type calcStep struct {
+	Left      string `json:"left"`
+	Operator  string `json:"operator"`
+	Right     string `json:"right"`
+	Result    string `json:"result"` // empty if out of range
+	Decimal   int    `json:"decimal"`
+	Octal     string `json:"octal"`
+	Remainder int    `json:"remainder,omitempty"` // of a division that does not come out even
+	// the operands as decimal numbers
+	leftValue, rightValue int
+}
+
+// the text of a step, e.g. "mevol + pxey = mevopey (16 + 3 = 19, octal 020 + 03 = 023)"
+func (s calcStep) String() string {
+	text := fmt.Sprintf("%s %s %s = %s (%d %s %d = %d, octal %#o %s %#o = %s)",
+		s.Left, s.Operator, s.Right, s.Result, s.leftValue, s.Operator, s.rightValue, s.Decimal, s.leftValue, s.Operator, s.rightValue, s.Octal)
+	if s.Remainder != 0 {
+		text += fmt.Sprintf(", remainder %d", s.Remainder)
+	}
+	return text
+}
+
+// The worked solution, one step per line, then the result
+func (c calculation) text() string {
+	var b bytes.Buffer
+	b.WriteString(c.Expression + "\n")
+	for i, step := range c.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+	}
+	fmt.Fprintf(&b, "= %s (%s, octal %s)\n", c.Result.Name, c.Result.Decimal, c.Result.Octal)
+	return b.String()
+}
+
+// calculator evaluates an expression token by token, recording each step.
+type calculator struct {
+	tokens []string
+	pos    int
+	steps  []calcStep
+}
+
+// Evaluate an expression of Na'vi number words, + - * / and parentheses
+func getCalculation(w http.ResponseWriter, r *http.Request) {
+	expr := strings.TrimSpace(r.URL.Query().Get("expr"))
+	if expr == "" {
+		writeError(w, errMissingParam("expr"))
+		return
+	}
+	if len(expr) > calcMaxLength {
+		writeError(w, errTooLarge(fmt.Sprintf("expression longer than %d bytes", calcMaxLength)))
+		return
+	}
+
+	result, e := calculate(expr)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	render(w, r, result)
+}
+
+// Evaluate expr with its worked solution, or return an error naming the token at fault
+func calculate(expr string) (calculation, *apiError) {
+	c := &calculator{tokens: calcTokens(expr), steps: []calcStep{}}
+	value, e := c.expression()
+	if e == nil && c.pos < len(c.tokens) {
+		e = errInvalidParam("expr", c.tokens[c.pos], "an operator", "")
+	}
+	if e != nil {
+		return calculation{}, e
+	}
+	return calculation{Expression: expr, Result: newNumber(value), Steps: c.steps}, nil
+}
+
+// split expr into number words, operators and parentheses
+func calcTokens(expr string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, c := range expr {
+		switch {
+		case unicode.IsSpace(c):
+			flush()
+		case calcOperators[string(c)] != "" || c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			word.WriteRune(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// the next token, or "" at the end
+func (c *calculator) peek() string {
+	if c.pos < len(c.tokens) {
+		return c.tokens[c.pos]
+	}
+	return ""
+}
+
+// expression: term, then any number of + or - and a term
+func (c *calculator) expression() (int, *apiError) {
+	left, e := c.term()
+	for e == nil && (c.peek() == "+" || c.peek() == "-") {
+		op := c.peek()
+		c.pos++
+		var right int
+		if right, e = c.term(); e == nil {
+			left, e = c.apply(left, op, right)
+		}
+	}
+	return left, e
+}
+
+// term: factor, then any number of * or / and a factor
+func (c *calculator) term() (int, *apiError) {
+	left, e := c.factor()
+	for e == nil && (c.peek() == "*" || c.peek() == "×" || c.peek() == "/" || c.peek() == "÷") {
+		op := c.peek()
+		c.pos++
+		var right int
+		if right, e = c.factor(); e == nil {
+			left, e = c.apply(left, op, right)
+		}
+	}
+	return left, e
+}
+
+// factor: a Na'vi number word or an expression in parentheses
+func (c *calculator) factor() (int, *apiError) {
+	token := c.peek()
+	switch {
+	case token == "":
+		return 0, errInvalidParam("expr", "", "a number word or ( after the last operator", "")
+	case token == "(":
+		c.pos++
+		value, e := c.expression()
+		if e != nil {
+			return 0, e
+		}
+		if c.peek() != ")" {
+			return 0, errInvalidParam("expr", c.peek(), ")", "")
+		}
+		c.pos++
+		return value, nil
+	case token == ")" || calcOperators[token] != "":
+		return 0, errInvalidParam("expr", token, "a number word or (", "")
+	}
+	c.pos++
+	value, err := naviToNumber(token)
+	if err != nil {
+		return 0, errInvalidParam("expr", token, "a Na'vi number word", "invalidNumericError")
+	}
+	return value, nil
+}
+
+// Work out left op right as a step. Division rounds down and keeps the remainder.
+func (c *calculator) apply(left int, op string, right int) (int, *apiError) {
+	leftWord, _ := fwew.NumberToNavi(left)
+	rightWord, _ := fwew.NumberToNavi(right)
+	step := calcStep{Left: leftWord, Operator: calcOperators[op], Right: rightWord, leftValue: left, rightValue: right}
+	switch step.Operator {
+	case "+":
+		step.Decimal = left + right
+	case "−":
+		step.Decimal = left - right
+	case "×":
+		step.Decimal = left * right
+	case "÷":
+		if right == 0 {
+			e := errInvalidParam("expr", leftWord+" / "+rightWord, "no division by kew (zero)", "")
+			e.Step = &step
+			return 0, e
+		}
+		step.Decimal, step.Remainder = left/right, left%right
+	}
+
+	step.Octal = fmt.Sprintf("%#o", step.Decimal)
+	word, err := fwew.NumberToNavi(step.Decimal)
+	if err != nil {
+		return 0, errOutOfRange(step)
+	}
+	step.Result = word
+	c.steps = append(c.steps, step)
+	return step.Decimal, nil
+}
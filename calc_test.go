@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCalcTokens(t *testing.T) {
+	tests := map[string][]string{
+		"mevol + pxey":         {"mevol", "+", "pxey"},
+		"mune*(pxey+'aw)":      {"mune", "*", "(", "pxey", "+", "'aw", ")"},
+		"  vol ÷ mune ":        {"vol", "÷", "mune"},
+		"kinä×kinä":            {"kinä", "×", "kinä"},
+		"vol-'aw":              {"vol", "-", "'aw"},
+		"":                     nil,
+		"zamtsìvosìng / mevol": {"zamtsìvosìng", "/", "mevol"},
+	}
+	for expr, want := range tests {
+		if got := calcTokens(expr); !slices.Equal(got, want) {
+			t.Errorf("calcTokens(%q) = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		expr   string
+		result string
+		steps  []string
+	}{
+		{"mevol + pxey", "mevopey", []string{"mevol + pxey = mevopey (16 + 3 = 19, octal 020 + 03 = 023)"}},
+		{"mune * (pxey + 'aw)", "vol", []string{
+			"pxey + 'aw = tsìng (3 + 1 = 4, octal 03 + 01 = 04)",
+			"mune × tsìng = vol (2 × 4 = 8, octal 02 × 04 = 010)",
+		}},
+		{"mune + pxey * mrr", "mevolaw", []string{
+			"pxey × mrr = vohin (3 × 5 = 15, octal 03 × 05 = 017)",
+			"mune + vohin = mevolaw (2 + 15 = 17, octal 02 + 017 = 021)",
+		}},
+		{"vol - 'aw - 'aw", "pukap", []string{
+			"vol − 'aw = kinä (8 − 1 = 7, octal 010 − 01 = 07)",
+			"kinä − 'aw = pukap (7 − 1 = 6, octal 07 − 01 = 06)",
+		}},
+		{"vol / pxey", "mune", []string{"vol ÷ pxey = mune (8 ÷ 3 = 2, octal 010 ÷ 03 = 02), remainder 2"}},
+		{"kina ÷ mune", "pxey", []string{"kinä ÷ mune = pxey (7 ÷ 2 = 3, octal 07 ÷ 02 = 03), remainder 1"}},
+		{"((tsivol))", "tsìvol", nil},
+	}
+	for _, tt := range tests {
+		c, e := calculate(tt.expr)
+		if e != nil {
+			t.Errorf("calculate(%q): %s", tt.expr, e.Message)
+			continue
+		}
+		var steps []string
+		for _, step := range c.Steps {
+			steps = append(steps, step.String())
+		}
+		if c.Result.Name != tt.result || !slices.Equal(steps, tt.steps) {
+			t.Errorf("calculate(%q) = %s %q, want %s %q", tt.expr, c.Result.Name, steps, tt.result, tt.steps)
+		}
+	}
+}
+
+func TestCalculateErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		code string
+		step bool // whether the error carries the failed step
+	}{
+		{"vol +", codeInvalidParameter, false},
+		{"+ vol", codeInvalidParameter, false},
+		{"vol vol", codeInvalidParameter, false},
+		{"(vol + mune", codeInvalidParameter, false},
+		{"vol + tute", codeInvalidParameter, false},
+		{"vol / kew", codeInvalidParameter, true},
+		{"kew - 'aw", codeOutOfRange, true},
+		{"zazam * vol", codeOutOfRange, true},
+	}
+	for _, tt := range tests {
+		_, e := calculate(tt.expr)
+		if e == nil {
+			t.Errorf("calculate(%q): no error", tt.expr)
+			continue
+		}
+		if e.Code != tt.code || e.Param != "expr" || (e.Step != nil) != tt.step {
+			t.Errorf("calculate(%q): %s error for %q with step %v, want %s for expr with step %v",
+				tt.expr, e.Code, e.Param, e.Step != nil, tt.code, tt.step)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// values of the conjugation query parameters
+var (
+	tenses      = []string{"present", "past", "recent-past", "future", "near-future"}
+	aspects     = []string{"none", "perfective", "imperfective"}
+	moods       = []string{"indicative", "subjunctive"}
+	participles = []string{"none", "active", "passive"}
+	evidentials = []string{"none", "inferential"}
+	affects     = []string{"none", "laudative", "pejorative"}
+)
+
+// first position infixes by tense and aspect (indicative mood)
+var tenseAspectInfixes = map[string][3]string{
+	// none, perfective, imperfective
+	"present":     {"", "ol", "er"},
+	"past":        {"am", "alm", "arm"},
+	"recent-past": {"ìm", "ìlm", "ìrm"},
+	"future":      {"ay", "aly", "ary"},
+	"near-future": {"ìy", "ìly", "ìry"},
+}
+
+// pre-first and second position infixes by the category they mark;
+// every other infix goes in the first position
+var (
+	prefirstInfixes = map[string]string{"reflexive": "äp", "causative": "eyk", "reflexive causative": "äpeyk"}
+	secondInfixes   = map[string]string{"inferential": "ats", "laudative": "ei", "pejorative": "äng", "formal": "uy"}
+)
+
+// other spellings fwew-lib finds of the infixes above
+var infixSpellings = map[string]string{"ap": "äp", "ep": "äp", "epeyk": "äpeyk", "eiy": "ei", "eng": "äng", "ang": "äng"}
+
+// the infix position of infix: 0 for pre-first, 1 for first, 2 for second
+func infixPosition(infix string) int {
+	infix = firstOf(infixSpellings[infix], infix)
+	for position, infixes := range map[int]map[string]string{0: prefirstInfixes, 2: secondInfixes} {
+		for _, i := range infixes {
+			if i == infix {
+				return position
+			}
+		}
+	}
+	return 1
+}
+
+// Na'vi letters and their IPA; anything else is written as is
+var naviIPA = map[string]string{
+	"a": "a", "ä": "æ", "e": "ɛ", "i": "i", "ì": "ɪ", "o": "o", "u": "u",
+	"ll": "l̩", "rr": "r̩", "r": "ɾ", "y": "j", "'": "ʔ", "ng": "ŋ",
+	"ts": "t͡s", "tx": "tʼ", "px": "pʼ", "kx": "kʼ",
+}
+
+// second consonants that may follow f, s or ts at the start of a syllable
+var clusterSeconds = []string{"p", "t", "k", "px", "tx", "kx", "l", "r", "m", "n", "ng", "w", "y"}
+
+// conjugationRequest is the set of grammatical categories to conjugate a verb for.
+type conjugationRequest struct {
+	Tense      string
+	Aspect     string
+	Mood       string
+	Intent     bool
+	Participle string
+	Evidential string
+	Affect     string
+	Formal     bool
+	Causative  bool
+	Reflexive  bool
+}
+
+// conjugation is one generated verb form.
+type conjugation struct {
+	Verb       string   `json:"verb"`
+	ID         string   `json:"id"`
+	Tense      string   `json:"tense,omitempty"`
+	Aspect     string   `json:"aspect,omitempty"`
+	Mood       string   `json:"mood,omitempty"`
+	Intent     bool     `json:"intent,omitempty"`
+	Participle string   `json:"participle,omitempty"`
+	Infixes    []string `json:"infixes"` // in slot order: pre-first, first, second
+	Form       string   `json:"form"`
+	Syllables  string   `json:"syllables"`
+	IPA        string   `json:"ipa"`
+	Stressed   int      `json:"stressed"` // 1-based index of the stressed syllable, 0 if unknown
+}
+
+// unit is one letter of a generated form.
+type unit struct {
+	text    string
+	nucleus int // the number of the root syllable whose nucleus this is, or -1
+}
+
+// Conjugate a verb for the categories in the query string, see parseConjugation
+func getConjugation(w http.ResponseWriter, r *http.Request) {
+	req, e := parseConjugation(r.URL.Query())
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	slots, e := req.infixes()
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	verb, e := lookUpVerb(r)
+	if e != nil {
+		writeLookUpError(w, r, e)
+		return
+	}
+
+	c := conjugate(verb, slots)
+	req.describe(&c)
+	setResultCount(r, 1)
+	render(w, r, c)
+}
+
+// Conjugate a verb for every tense, aspect, mood and participle.
+// The pre-first and second position categories of the query string apply to every form.
+func getParadigm(w http.ResponseWriter, r *http.Request) {
+	base, e := parseConjugation(r.URL.Query())
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	for _, name := range []string{"tense", "aspect", "mood", "intent", "participle"} {
+		if r.URL.Query().Has(name) {
+			writeError(w, errInvalidCombination(name, name+" is set by every row of the paradigm"))
+			return
+		}
+	}
+	if _, e := base.infixes(); e != nil {
+		writeError(w, e)
+		return
+	}
+	verb, e := lookUpVerb(r)
+	if e != nil {
+		writeLookUpError(w, r, e)
+		return
+	}
+
+	var rows []conjugationRequest
+	for _, mood := range moods {
+		for _, tense := range tenses {
+			for _, aspect := range aspects {
+				for _, intent := range []bool{false, true} {
+					req := base
+					req.Tense = nonDefault(tense, tenses)
+					req.Aspect = nonDefault(aspect, aspects)
+					req.Mood = nonDefault(mood, moods)
+					req.Intent = intent
+					rows = append(rows, req)
+				}
+			}
+		}
+	}
+	// participles take no second position infix, so with evidential, affect or formal
+	// there are none; neither is there a passive one of a reflexive verb
+	for _, participle := range participles[1:] {
+		req := base
+		req.Participle = participle
+		if _, e := req.infixes(); e == nil {
+			rows = append(rows, req)
+		}
+	}
+
+	var paradigm []conjugation
+	for _, req := range rows {
+		slots, e := req.infixes()
+		if e != nil {
+			continue // not a valid combination
+		}
+		c := conjugate(verb, slots)
+		req.describe(&c)
+		paradigm = append(paradigm, c)
+	}
+
+	setResultCount(r, len(paradigm))
+	render(w, r, paradigm)
+}
+
+// Read a conjugationRequest from the query string:
+//
+//	tense       present (default), past, recent-past, future or near-future
+//	aspect      none (default), perfective or imperfective
+//	mood        indicative (default) or subjunctive
+//	intent      intended future, with tense=future or near-future (default false)
+//	participle  none (default), active or passive
+//	evidential  none (default) or inferential
+//	affect      none (default), laudative or pejorative
+//	formal      ceremonial speech (default false)
+//	causative   (default false)
+//	reflexive   (default false)
+func parseConjugation(query url.Values) (req conjugationRequest, e *apiError) {
+	choose := func(name string, values []string) (string, *apiError) {
+		value := query.Get(name)
+		if value != "" && !slices.Contains(values, value) {
+			return "", errInvalidParam(name, value, strings.Join(values, ", "), "")
+		}
+		return nonDefault(value, values), nil
+	}
+	flag := func(name string) bool {
+		var b bool
+		if e == nil {
+			b, e = boolParam(query.Get(name), name, false)
+		}
+		return b
+	}
+
+	for _, c := range []struct {
+		name   string
+		values []string
+		dest   *string
+	}{
+		{"tense", tenses, &req.Tense},
+		{"aspect", aspects, &req.Aspect},
+		{"mood", moods, &req.Mood},
+		{"participle", participles, &req.Participle},
+		{"evidential", evidentials, &req.Evidential},
+		{"affect", affects, &req.Affect},
+	} {
+		if *c.dest, e = choose(c.name, c.values); e != nil {
+			return
+		}
+	}
+	req.Intent = flag("intent")
+	req.Formal = flag("formal")
+	req.Causative = flag("causative")
+	req.Reflexive = flag("reflexive")
+	return
+}
+
+// value, or "" if it is the default, the first of values
+func nonDefault(value string, values []string) string {
+	if value == values[0] {
+		return ""
+	}
+	return value
+}
+
+// fill in the categories of c from req, defaults included
+func (req conjugationRequest) describe(c *conjugation) {
+	if req.Participle != "" {
+		c.Participle = req.Participle
+		return
+	}
+	c.Tense = firstOf(req.Tense, tenses[0])
+	c.Aspect = firstOf(req.Aspect, aspects[0])
+	c.Mood = firstOf(req.Mood, moods[0])
+	c.Intent = req.Intent
+}
+
+// The infixes of the pre-first, first and second positions for req,
+// or an invalid_combination error
+func (req conjugationRequest) infixes() (slots [3]string, e *apiError) {
+	switch {
+	case req.Reflexive && req.Causative:
+		slots[0] = prefirstInfixes["reflexive causative"]
+	case req.Reflexive:
+		slots[0] = prefirstInfixes["reflexive"]
+	case req.Causative:
+		slots[0] = prefirstInfixes["causative"]
+	}
+
+	if req.Participle != "" {
+		for _, other := range []struct {
+			name string
+			set  bool
+		}{
+			{"tense", req.Tense != ""}, {"aspect", req.Aspect != ""}, {"mood", req.Mood != ""}, {"intent", req.Intent},
+			{"evidential", req.Evidential != ""}, {"affect", req.Affect != ""}, {"formal", req.Formal},
+		} {
+			if other.set {
+				return slots, errInvalidCombination("participle", "a participle has no "+other.name)
+			}
+		}
+		// a reflexive verb is not passive, unless it is also causative
+		if req.Participle == "passive" && slots[0] == prefirstInfixes["reflexive"] {
+			return slots, errInvalidCombination("participle", "a reflexive verb has no passive participle")
+		}
+		slots[1] = map[string]string{"active": "us", "passive": "awn"}[req.Participle]
+	} else {
+		tense := req.Tense
+		if tense == "" {
+			tense = "present"
+		}
+		if req.Intent && tense != "future" && tense != "near-future" {
+			return slots, errInvalidCombination("intent", "intent is only marked in the future and near future")
+		}
+		if req.Intent && req.Aspect != "" {
+			return slots, errInvalidCombination("intent", "the intended future has no aspect")
+		}
+		if req.Mood == "subjunctive" {
+			switch {
+			case req.Intent:
+				return slots, errInvalidCombination("intent", "the subjunctive has no intended future")
+			case tense == "present":
+				slots[1] = map[string]string{"": "iv", "perfective": "ilv", "imperfective": "irv"}[req.Aspect]
+			case req.Aspect != "":
+				return slots, errInvalidCombination("aspect", "the subjunctive only has aspect in the present")
+			case tense == "past":
+				slots[1] = "imv"
+			case tense == "future":
+				slots[1] = "ìyev"
+			default:
+				return slots, errInvalidCombination("tense", "the subjunctive has no "+tense+" tense")
+			}
+		} else {
+			slots[1] = tenseAspectInfixes[tense][slices.Index(aspects, firstOf(req.Aspect, aspects[0]))]
+			if req.Intent {
+				slots[1] = map[string]string{"future": "asy", "near-future": "ìsy"}[tense]
+			}
+		}
+	}
+
+	var second []string
+	if req.Evidential != "" {
+		second = append(second, "evidential")
+		slots[2] = secondInfixes[req.Evidential]
+	}
+	if req.Affect != "" {
+		second = append(second, "affect")
+		slots[2] = secondInfixes[req.Affect]
+	}
+	if req.Formal {
+		second = append(second, "formal")
+		slots[2] = secondInfixes["formal"]
+	}
+	if len(second) > 1 {
+		return slots, errInvalidCombination(second[len(second)-1], "only one of "+strings.Join(second, ", ")+" can be marked at once")
+	}
+	return slots, nil
+}
+
+// the first of values that is not empty
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// The dictionary entry of the verb path variable: a no_results error if there is none,
+// an invalid_parameter error if it is not a verb
+func lookUpVerb(r *http.Request) (fwew.Word, *apiError) {
+	return lookUpHeadword("verb", mux.Vars(r)["verb"], "a verb", hasInfixes)
+}
+
+// whether word is a verb with known infix positions
+func hasInfixes(word fwew.Word) bool {
+	pos := word.PartOfSpeech
+	return (strings.HasPrefix(pos, "v") || strings.HasPrefix(pos, "svin")) &&
+		strings.Contains(word.InfixLocations, "<1>")
+}
+
+// Put the infixes of slots into the infix positions of verb
+func conjugate(verb fwew.Word, slots [3]string) conjugation {
+	c := conjugation{Verb: verb.Navi, ID: verb.ID, Infixes: []string{}}
+	for _, infix := range slots {
+		if infix != "" {
+			c.Infixes = append(c.Infixes, infix)
+		}
+	}
+
+	stressed, _ := strconv.Atoi(verb.Stressed)
+
+	var forms, syllables, ipas []string
+	n := 0
+	for _, word := range splitWords(infixUnits(verb.InfixLocations, slots)) {
+		var form strings.Builder
+		var texts, sounds []string
+		for _, syllable := range syllabify(word) {
+			n++
+			var text, sound strings.Builder
+			for _, u := range syllable {
+				text.WriteString(u.text)
+				sound.WriteString(firstOf(naviIPA[u.text], u.text))
+				if u.nucleus >= 0 && u.nucleus == stressed-1 {
+					c.Stressed = n
+				}
+			}
+			texts = append(texts, text.String())
+			if c.Stressed == n {
+				sounds = append(sounds, "ˈ"+sound.String())
+			} else {
+				sounds = append(sounds, sound.String())
+			}
+			form.WriteString(text.String())
+		}
+		forms = append(forms, form.String())
+		syllables = append(syllables, strings.Join(texts, "-"))
+		ipas = append(ipas, strings.Join(sounds, "."))
+	}
+
+	c.Form = strings.Join(forms, " ")
+	c.Syllables = strings.Join(syllables, " ")
+	c.IPA = strings.Join(ipas, " ")
+	return c
+}
+
+// piece is a stretch of root letters, or an infix position.
+type piece struct {
+	slot  int // 0, 1 or 2 for an infix position, -1 for root letters
+	units []unit
+}
+
+// The letters of the infix locations of a verb, such as "t<0><1>ar<2>on",
+// with the infixes of slots put in. Root nuclei are numbered by syllable.
+func infixUnits(locations string, slots [3]string) []unit {
+	var pieces []piece
+	for len(locations) > 0 {
+		start := strings.Index(locations, "<")
+		if start < 0 || start+2 >= len(locations) || locations[start+2] != '>' || locations[start+1] < '0' || locations[start+1] > '2' {
+			pieces = append(pieces, piece{slot: -1, units: toUnits(locations)})
+			break
+		}
+		if start > 0 {
+			pieces = append(pieces, piece{slot: -1, units: toUnits(locations[:start])})
+		}
+		pieces = append(pieces, piece{slot: int(locations[start+1] - '0')})
+		locations = locations[start+3:]
+	}
+
+	// number the nuclei of the root alone
+	var root []*unit
+	for _, p := range pieces {
+		for i := range p.units {
+			root = append(root, &p.units[i])
+		}
+	}
+	texts := make([]string, len(root))
+	for i, u := range root {
+		texts[i] = u.text
+	}
+	n := 0
+	for i, u := range root {
+		if isNucleus(texts, i) {
+			u.nucleus = n
+			n++
+		}
+	}
+
+	var units []unit
+	for i, p := range pieces {
+		if p.slot < 0 {
+			units = append(units, p.units...)
+			continue
+		}
+		infix := slots[p.slot]
+		if infix == "" {
+			continue
+		}
+		next := nextRootUnit(pieces[i+1:], slots)
+		// <ei> is <eiy> before a vowel
+		if infix == "ei" && next != nil && isVowel(next.text) {
+			infix = "eiy"
+		}
+		add := toUnits(infix)
+		// a syllabic ll or rr merges into an infix ending in the same letter:
+		// k<ol>llkxem is kolkxem, but k<am>llkxem stays kamllkxem
+		if next != nil && next.nucleus >= 0 && (next.text == "ll" || next.text == "rr") &&
+			add[len(add)-1].text == next.text[:1] {
+			for j := len(add) - 1; j >= 0; j-- {
+				if isVowel(add[j].text) {
+					add[j].nucleus = next.nucleus
+					break
+				}
+			}
+			next.text = ""
+		}
+		units = append(units, add...)
+	}
+
+	return slices.DeleteFunc(units, func(u unit) bool { return u.text == "" })
+}
+
+// the first root letter of pieces, or nil if an infix or the end comes first
+func nextRootUnit(pieces []piece, slots [3]string) *unit {
+	for _, p := range pieces {
+		if p.slot >= 0 {
+			if slots[p.slot] != "" {
+				return nil
+			}
+			continue
+		}
+		if len(p.units) > 0 {
+			return &p.units[0]
+		}
+	}
+	return nil
+}
+
+// the letters of s, none of them a nucleus yet
+func toUnits(s string) []unit {
+	var units []unit
+	for _, text := range naviUnits(s) {
+		units = append(units, unit{text: text, nucleus: -1})
+	}
+	return units
+}
+
+// the letters of each word of units
+func splitWords(units []unit) [][]unit {
+	var words [][]unit
+	var word []unit
+	for _, u := range units {
+		if strings.TrimSpace(u.text) == "" {
+			if len(word) > 0 {
+				words = append(words, word)
+			}
+			word = nil
+			continue
+		}
+		word = append(word, u)
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+	return words
+}
+
+func isVowel(text string) bool {
+	return slices.Contains([]string{"a", "ä", "e", "i", "ì", "o", "u"}, text)
+}
+
+// whether texts[i] is the nucleus of a syllable: a vowel, or ll or rr between consonants
+func isNucleus(texts []string, i int) bool {
+	if isVowel(texts[i]) {
+		return true
+	}
+	if texts[i] != "ll" && texts[i] != "rr" {
+		return false
+	}
+	return !(i > 0 && isVowel(texts[i-1])) && !(i+1 < len(texts) && isVowel(texts[i+1]))
+}
+
+// Split one word into syllables. Consonants between nuclei start the next
+// syllable as far as Na'vi allows: one consonant, or f, s or ts and another;
+// any others close the syllable before.
+func syllabify(word []unit) [][]unit {
+	texts := make([]string, len(word))
+	for i, u := range word {
+		texts[i] = u.text
+	}
+	var nuclei []int
+	for i := range texts {
+		if isNucleus(texts, i) {
+			nuclei = append(nuclei, i)
+		}
+	}
+	if len(nuclei) == 0 {
+		return [][]unit{word}
+	}
+
+	var syllables [][]unit
+	start := 0
+	for k := 0; k+1 < len(nuclei); k++ {
+		consonants := nuclei[k+1] - nuclei[k] - 1
+		onset := min(consonants, 1)
+		if consonants >= 2 {
+			first, second := texts[nuclei[k+1]-2], texts[nuclei[k+1]-1]
+			if slices.Contains([]string{"f", "s", "ts"}, first) && slices.Contains(clusterSeconds, second) {
+				onset = 2
+			}
+		}
+		end := nuclei[k+1] - onset
+		syllables = append(syllables, word[start:end])
+		start = end
+	}
+	return append(syllables, word[start:])
+}
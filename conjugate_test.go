@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// verbs as they are in the dictionary
+var (
+	taron      = fwew.Word{ID: "1", Navi: "taron", InfixLocations: "t<0><1>ar<2>on", PartOfSpeech: "vtr.", Stressed: "1"}
+	kame       = fwew.Word{ID: "2", Navi: "kame", InfixLocations: "k<0><1>am<2>e", PartOfSpeech: "vtr.", Stressed: "1"}
+	kllkxem    = fwew.Word{ID: "3", Navi: "kllkxem", InfixLocations: "k<0><1>llkx<2>em", PartOfSpeech: "vin.", Stressed: "1"}
+	yom        = fwew.Word{ID: "6", Navi: "yom", InfixLocations: "y<0><1>o<2>m", PartOfSpeech: "vtr.", Stressed: "1"}
+	lu         = fwew.Word{ID: "4", Navi: "lu", InfixLocations: "l<0><1>u<2>", PartOfSpeech: "vin.", Stressed: "1"}
+	tingMikyun = fwew.Word{ID: "5", Navi: "tìng mikyun", InfixLocations: "t<0><1>ìng mikyun<2>", PartOfSpeech: "vin.", Stressed: "1"}
+)
+
+func TestConjugate(t *testing.T) {
+	tests := []struct {
+		verb      fwew.Word
+		req       conjugationRequest
+		form      string
+		syllables string
+		stressed  int
+	}{
+		{taron, conjugationRequest{}, "taron", "ta-ron", 1},
+		{taron, conjugationRequest{Tense: "past"}, "tamaron", "ta-ma-ron", 2},
+		{taron, conjugationRequest{Tense: "past", Aspect: "perfective"}, "talmaron", "tal-ma-ron", 2},
+		{taron, conjugationRequest{Tense: "recent-past", Aspect: "imperfective"}, "tìrmaron", "tìr-ma-ron", 2},
+		{taron, conjugationRequest{Tense: "future"}, "tayaron", "ta-ya-ron", 2},
+		{taron, conjugationRequest{Tense: "near-future", Intent: true}, "tìsyaron", "tì-sya-ron", 2},
+		{taron, conjugationRequest{Mood: "subjunctive"}, "tivaron", "ti-va-ron", 2},
+		{taron, conjugationRequest{Mood: "subjunctive", Tense: "future"}, "tìyevaron", "tì-ye-va-ron", 3},
+		{taron, conjugationRequest{Participle: "active"}, "tusaron", "tu-sa-ron", 2},
+		{taron, conjugationRequest{Participle: "passive"}, "tawnaron", "taw-na-ron", 2},
+		{taron, conjugationRequest{Participle: "passive", Reflexive: true, Causative: true}, "täpeykawnaron", "tä-pey-kaw-na-ron", 4},
+		{taron, conjugationRequest{Causative: true}, "teykaron", "tey-ka-ron", 2},
+		{taron, conjugationRequest{Causative: true, Tense: "future"}, "teykayaron", "tey-ka-ya-ron", 3},
+		{taron, conjugationRequest{Reflexive: true, Causative: true}, "täpeykaron", "tä-pey-ka-ron", 3},
+		{taron, conjugationRequest{Affect: "pejorative"}, "tarängon", "ta-rä-ngon", 1},
+		{yom, conjugationRequest{Affect: "laudative"}, "yoeim", "yo-e-im", 1},
+		{kame, conjugationRequest{Evidential: "inferential"}, "kamatse", "ka-ma-tse", 1},
+		{kllkxem, conjugationRequest{Aspect: "perfective"}, "kolkxem", "kol-kxem", 1},
+		{kllkxem, conjugationRequest{Tense: "past"}, "kamllkxem", "ka-mll-kxem", 2},
+		{lu, conjugationRequest{Tense: "past"}, "lamu", "la-mu", 2},
+		{lu, conjugationRequest{Formal: true}, "luuy", "lu-uy", 1},
+		{tingMikyun, conjugationRequest{Tense: "past"}, "tamìng mikyun", "ta-mìng mik-yun", 2},
+	}
+	for _, tt := range tests {
+		slots, e := tt.req.infixes()
+		if e != nil {
+			t.Errorf("%s %+v: %v", tt.verb.Navi, tt.req, e.Message)
+			continue
+		}
+		c := conjugate(tt.verb, slots)
+		if c.Form != tt.form || c.Syllables != tt.syllables || c.Stressed != tt.stressed {
+			t.Errorf("%s %+v = %q %q stressed %d, want %q %q stressed %d",
+				tt.verb.Navi, tt.req, c.Form, c.Syllables, c.Stressed, tt.form, tt.syllables, tt.stressed)
+		}
+	}
+}
+
+func TestInfixesInvalidCombination(t *testing.T) {
+	tests := []struct {
+		req   conjugationRequest
+		param string
+	}{
+		{conjugationRequest{Participle: "active", Tense: "past"}, "participle"},
+		{conjugationRequest{Intent: true}, "intent"},
+		{conjugationRequest{Intent: true, Tense: "future", Aspect: "perfective"}, "intent"},
+		{conjugationRequest{Mood: "subjunctive", Tense: "past", Aspect: "imperfective"}, "aspect"},
+		{conjugationRequest{Mood: "subjunctive", Tense: "recent-past"}, "tense"},
+		{conjugationRequest{Evidential: "inferential", Formal: true}, "formal"},
+		{conjugationRequest{Participle: "active", Evidential: "inferential"}, "participle"},
+		{conjugationRequest{Participle: "active", Affect: "laudative"}, "participle"},
+		{conjugationRequest{Participle: "passive", Formal: true}, "participle"},
+		{conjugationRequest{Participle: "passive", Reflexive: true}, "participle"},
+	}
+	for _, tt := range tests {
+		_, e := tt.req.infixes()
+		if e == nil {
+			t.Errorf("%+v: no error", tt.req)
+			continue
+		}
+		if e.Code != codeInvalidCombo || e.Param != tt.param {
+			t.Errorf("%+v: %s error for %q, want %s for %q", tt.req, e.Code, e.Param, codeInvalidCombo, tt.param)
+		}
+	}
+}
+
+func TestSyllabify(t *testing.T) {
+	tests := map[string]string{
+		"skxawng":   "skxawng",
+		"tìkangkem": "tì-kang-kem",
+		"fpeio":     "fpe-i-o",
+		"tsmukan":   "tsmu-kan",
+		"ikran":     "ik-ran",
+		"kllkxem":   "kll-kxem",
+	}
+	for word, want := range tests {
+		var got []string
+		for _, syllable := range syllabify(toUnits(word)) {
+			var s strings.Builder
+			for _, u := range syllable {
+				s.WriteString(u.text)
+			}
+			got = append(got, s.String())
+		}
+		if joined := strings.Join(got, "-"); joined != want {
+			t.Errorf("syllabify(%q) = %q, want %q", word, joined, want)
+		}
+	}
+}
+
+// the paradigm only has the participles that can take the categories of the query
+func TestParadigmParticiples(t *testing.T) {
+	tests := map[string][]string{
+		"":                              {"active", "passive"},
+		"reflexive=true":                {"active"},
+		"reflexive=true&causative=true": {"active", "passive"},
+		"affect=laudative":              nil,
+		"evidential=inferential":        nil,
+		"formal=true":                   nil,
+	}
+	for query, want := range tests {
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/conjugate/taron/paradigm?"+query, nil), map[string]string{"verb": "taron"})
+		w := httptest.NewRecorder()
+		getParadigm(w, r)
+		var paradigm []conjugation
+		if err := json.Unmarshal(w.Body.Bytes(), &paradigm); err != nil {
+			t.Errorf("%s: %v: %s", query, err, w.Body)
+			continue
+		}
+		var got []string
+		for _, c := range paradigm {
+			if c.Participle != "" {
+				got = append(got, c.Participle)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("paradigm?%s has participles %q, want %q", query, got, want)
+		}
+	}
+}
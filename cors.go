@@ -0,0 +1,122 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// response headers browsers may read besides the CORS-safelisted ones
+var corsExposedHeaders = []string{
+	"ETag", "Link", "X-Request-ID", "X-Seed", "X-Total-Count",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+}
+
+// fill in defaults for the CORS settings of config.
+// Credentials with "*" would let any site send credentialed requests, so that refuses to start.
+func defaultCORSConfig() {
+	if config.CORSOrigins == nil {
+		config.CORSOrigins = []string{"*"}
+	}
+	if config.CORSCredentials && slices.Contains(config.CORSOrigins, "*") {
+		log.Fatalf(`config: CORSCredentials cannot be used with "*" in CORSOrigins; list the allowed origins instead`)
+	}
+	if len(config.CORSMethods) == 0 {
+		config.CORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	if len(config.CORSHeaders) == 0 {
+		config.CORSHeaders = []string{
+			"Authorization", "Content-Type", "If-None-Match",
+			"X-API-Key", "X-Request-ID", "X-Fwew-Timestamp", "X-Fwew-Signature",
+		}
+	}
+	if config.CORSMaxAge == 0 {
+		config.CORSMaxAge = 600
+	}
+}
+
+// Whether origin is allowed by CORSOrigins. Entries are "*", an exact origin
+// such as "https://example.com", or a wildcard subdomain such as "https://*.example.com".
+// "*" never matches when credentials are allowed.
+func originAllowed(origin string) bool {
+	for _, allowed := range config.CORSOrigins {
+		if (allowed == "*" && !config.CORSCredentials) || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		prefix := strings.ToLower(scheme + "://")
+		suffix := strings.ToLower("." + host)
+		lower := strings.ToLower(origin)
+		if strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) && len(lower) > len(prefix)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// the methods the matched route accepts, limited to CORSMethods;
+// routes registered without methods are read-only
+func corsMethods(r *http.Request) []string {
+	methods := []string{http.MethodGet, http.MethodHead}
+	if route := mux.CurrentRoute(r); route != nil {
+		if routeMethods, err := route.GetMethods(); err == nil {
+			methods = slices.Clone(routeMethods)
+		}
+	}
+	return slices.DeleteFunc(methods, func(m string) bool {
+		return m == http.MethodOptions || !slices.Contains(config.CORSMethods, m)
+	})
+}
+
+// Add CORS headers for allowed origins according to config, and answer
+// OPTIONS requests, including preflights, for every registered route.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && originAllowed(origin)
+		header := w.Header()
+
+		// the allowed origin is echoed unless any origin is allowed without credentials,
+		// in which case responses do not depend on the Origin header
+		anyOrigin := slices.Contains(config.CORSOrigins, "*") && !config.CORSCredentials
+		if !anyOrigin {
+			addVary(header, "Origin")
+		}
+		if allowed {
+			if anyOrigin {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if config.CORSCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions || mux.CurrentRoute(r) == nil {
+			if allowed {
+				header.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		methods := corsMethods(r)
+		header.Set("Allow", strings.Join(append(slices.Clone(methods), http.MethodOptions), ", "))
+		if allowed && r.Header.Get("Access-Control-Request-Method") != "" {
+			addVary(header, "Access-Control-Request-Method")
+			addVary(header, "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			header.Set("Access-Control-Allow-Headers", strings.Join(config.CORSHeaders, ", "))
+			header.Set("Access-Control-Max-Age", strconv.Itoa(config.CORSMaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestOriginAllowed(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	tests := []struct {
+		origins     []string
+		credentials bool
+		origin      string
+		ok          bool
+	}{
+		{[]string{"*"}, false, "https://anywhere.net", true},
+		{[]string{"*"}, true, "https://anywhere.net", false},
+		{[]string{"https://example.com"}, false, "https://example.com", true},
+		{[]string{"https://example.com"}, false, "HTTPS://Example.com", true},
+		{[]string{"https://example.com"}, false, "http://example.com", false},
+		{[]string{"https://example.com"}, false, "https://example.com.evil.com", false},
+		{[]string{"https://*.example.com"}, false, "https://a.example.com", true},
+		{[]string{"https://*.example.com"}, true, "https://a.example.com", true},
+		{[]string{"https://*.example.com"}, false, "https://a.b.example.com", true},
+		{[]string{"https://*.example.com"}, false, "https://example.com", false},
+		{[]string{"https://*.example.com"}, false, "https://.example.com", false},
+		{[]string{"https://*.example.com"}, false, "https://evil-example.com", false},
+		{[]string{"https://*.example.com"}, false, "https://a.example.com.evil.com", false},
+		{[]string{"https://*.example.com"}, false, "http://a.example.com", false},
+		{[]string{"https://example.com", "https://*.fwew.app"}, true, "https://api.fwew.app", true},
+	}
+	for _, tt := range tests {
+		config.CORSOrigins = tt.origins
+		config.CORSCredentials = tt.credentials
+		if ok := originAllowed(tt.origin); ok != tt.ok {
+			t.Errorf("originAllowed(%q) with %q, credentials %v = %v, want %v", tt.origin, tt.origins, tt.credentials, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// cases and numbers of the declension table, in order
+var (
+	nounCases   = []string{"subjective", "agentive", "patientive", "dative", "genitive", "topical"}
+	nounNumbers = []string{"singular", "dual", "trial", "plural", "short plural"}
+)
+
+// number prefixes, all of which cause lenition
+var numberPrefixes = map[string]string{"dual": "me", "trial": "pxe", "plural": "ay"}
+
+// genitives of pronouns that do not follow the rules
+var irregularGenitives = map[string]string{"nga": "ngeyä", "po": "peyä", "fo": "feyä", "sno": "sneyä"}
+
+// declinedForm is one cell of a declension table.
+type declinedForm struct {
+	Noun         string   `json:"noun"`
+	ID           string   `json:"id"`
+	Number       string   `json:"number"`
+	Case         string   `json:"case"`
+	Form         string   `json:"form"`
+	Alternatives []string `json:"alternatives,omitempty"` // other correct forms, e.g. "oet" besides "oeti"
+	Recognized   bool     `json:"recognized"`             // whether searching Form finds the noun again
+	// the Alternatives searching which does not find the noun again
+	UnrecognizedAlternatives []string `json:"unrecognizedAlternatives,omitempty"`
+}
+
+// declension is the full table of a noun, row by row.
+type declension []declinedForm
+
+// The table as a grid of cases by numbers. Forms the analyser does not recognise are marked with *.
+func (d declension) text() string {
+	cells := map[[2]string]string{}
+	var numbers []string
+	unrecognized := false
+	for _, f := range d {
+		if !slices.Contains(numbers, f.Number) {
+			numbers = append(numbers, f.Number)
+		}
+		var forms []string
+		for i, form := range append([]string{f.Form}, f.Alternatives...) {
+			if (i == 0 && !f.Recognized) || (i > 0 && slices.Contains(f.UnrecognizedAlternatives, form)) {
+				form += "*"
+				unrecognized = true
+			}
+			forms = append(forms, form)
+		}
+		cells[[2]string{f.Case, f.Number}] = strings.Join(forms, ", ")
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	tw.Write([]byte("\t" + strings.Join(numbers, "\t") + "\n"))
+	for _, c := range nounCases {
+		row := []string{c}
+		for _, n := range numbers {
+			row = append(row, cells[[2]string{c, n}])
+		}
+		tw.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	tw.Flush()
+	if unrecognized {
+		b.WriteString("* not recognized by the analyser\n")
+	}
+	return b.String()
+}
+
+// Decline a noun or pronoun for every case and number
+func getDeclension(w http.ResponseWriter, r *http.Request) {
+	noun, e := lookUpHeadword("noun", mux.Vars(r)["noun"], "a noun or pronoun", isNoun)
+	if e != nil {
+		writeLookUpError(w, r, e)
+		return
+	}
+
+	table := decline(noun)
+	setResultCount(r, len(table))
+	render(w, r, table)
+}
+
+// whether word declines for case: nouns, proper nouns and pronouns
+func isNoun(word fwew.Word) bool {
+	for _, pos := range strings.Split(word.PartOfSpeech, ",") {
+		pos = strings.TrimSpace(pos)
+		if pos == "n." || pos == "pn." || pos == "prop.n." {
+			return true
+		}
+	}
+	return false
+}
+
+// The declension table of noun. Every form is searched to check it finds noun again.
+func decline(noun fwew.Word) declension {
+	numbers, stems := numberStems(noun)
+	var table declension
+	for _, number := range numbers {
+		for _, c := range nounCases {
+			forms := caseForms(stems[number], c)
+			f := declinedForm{Noun: noun.Navi, ID: noun.ID, Number: number, Case: c, Form: forms[0], Alternatives: forms[1:]}
+			f.Recognized = recognizes(f.Form, noun.ID)
+			for _, alternative := range f.Alternatives {
+				if !recognizes(alternative, noun.ID) {
+					f.UnrecognizedAlternatives = append(f.UnrecognizedAlternatives, alternative)
+				}
+			}
+			table = append(table, f)
+		}
+	}
+	return table
+}
+
+// The numbers noun declines for, in order, and its stem in each of them.
+// Pronouns only decline for case, their numbers being words of their own.
+func numberStems(noun fwew.Word) ([]string, map[string]string) {
+	stem := strings.ToLower(noun.Navi)
+	stems := map[string]string{nounNumbers[0]: stem}
+	numbers := []string{nounNumbers[0]}
+	if strings.Contains(noun.PartOfSpeech, "pn.") {
+		return numbers, stems
+	}
+
+	lenited, rule := lenite(stem)
+	for _, number := range nounNumbers[1:4] {
+		prefix := numberPrefixes[number]
+		// me- and pxe- merge with a following e: 'eylan -> meylan
+		if strings.HasSuffix(prefix, "e") && strings.HasPrefix(lenited, "e") {
+			prefix = strings.TrimSuffix(prefix, "e")
+		}
+		stems[number] = prefix + lenited
+		numbers = append(numbers, number)
+	}
+	// the plural prefix may be left out where lenition shows the plural
+	if rule != nil {
+		stems[nounNumbers[4]] = lenited
+		numbers = append(numbers, nounNumbers[4])
+	}
+	return numbers, stems
+}
+
+// The forms of stem in case c, the usual one first.
+// Endings depend on whether stem ends in a vowel, a diphthong or a consonant.
+func caseForms(stem string, c string) []string {
+	if c == "genitive" {
+		if genitive, ok := irregularGenitives[stem]; ok {
+			return []string{genitive}
+		}
+	}
+
+	vowel := false
+	if units := naviUnits(stem); len(units) > 0 {
+		last := units[len(units)-1]
+		diphthong := (last == "w" || last == "y") && len(units) > 1 && isVowel(units[len(units)-2])
+		vowel = isVowel(last) && !diphthong
+	}
+
+	switch c {
+	case "agentive":
+		if vowel {
+			return []string{stem + "l"}
+		}
+		return []string{stem + "ìl"}
+	case "patientive":
+		if vowel {
+			return []string{stem + "ti", stem + "t"}
+		}
+		return []string{stem + "it", stem + "ti"}
+	case "dative":
+		if vowel {
+			return []string{stem + "r", stem + "ru"}
+		}
+		return []string{stem + "ur"}
+	case "genitive":
+		switch {
+		case !vowel:
+			return []string{stem + "ä"}
+		case strings.HasSuffix(stem, "o") || strings.HasSuffix(stem, "u"):
+			return []string{stem + "ä"}
+		case strings.HasSuffix(stem, "ia"):
+			return []string{strings.TrimSuffix(stem, "a") + "ä"}
+		}
+		return []string{stem + "yä"}
+	case "topical":
+		if vowel {
+			return []string{stem + "ri"}
+		}
+		return []string{stem + "ìri"}
+	}
+	return []string{stem}
+}
+
+// whether searching form finds the word with the given ID
+func recognizes(form string, id string) bool {
+	groups, err := fwew.TranslateFromNaviHash(form, true, false, false)
+	if err != nil {
+		return false
+	}
+	for _, group := range groups {
+		for _, word := range group {
+			if word.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
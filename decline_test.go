@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+func TestCaseForms(t *testing.T) {
+	tests := []struct {
+		stem  string
+		c     string
+		forms []string
+	}{
+		{"tute", "subjective", []string{"tute"}},
+		{"tute", "agentive", []string{"tutel"}},
+		{"tute", "patientive", []string{"tuteti", "tutet"}},
+		{"tute", "dative", []string{"tuter", "tuteru"}},
+		{"tute", "genitive", []string{"tuteyä"}},
+		{"tute", "topical", []string{"tuteri"}},
+		{"ikran", "agentive", []string{"ikranìl"}},
+		{"ikran", "patientive", []string{"ikranit", "ikranti"}},
+		{"ikran", "dative", []string{"ikranur"}},
+		{"ikran", "genitive", []string{"ikranä"}},
+		{"ikran", "topical", []string{"ikranìri"}},
+		{"txampay", "agentive", []string{"txampayìl"}},
+		{"txampay", "genitive", []string{"txampayä"}},
+		{"oe", "agentive", []string{"oel"}},
+		{"oe", "patientive", []string{"oeti", "oet"}},
+		{"oe", "genitive", []string{"oeyä"}},
+		{"nga", "agentive", []string{"ngal"}},
+		{"nga", "genitive", []string{"ngeyä"}},
+		{"po", "genitive", []string{"peyä"}},
+		{"eywa", "genitive", []string{"eywayä"}},
+		{"soaia", "genitive", []string{"soaiä"}},
+		{"tsko", "genitive", []string{"tskoä"}},
+		{"kelku", "genitive", []string{"kelkuä"}},
+	}
+	for _, tt := range tests {
+		if forms := caseForms(tt.stem, tt.c); !slices.Equal(forms, tt.forms) {
+			t.Errorf("caseForms(%q, %q) = %q, want %q", tt.stem, tt.c, forms, tt.forms)
+		}
+	}
+}
+
+func TestNumberStems(t *testing.T) {
+	tests := []struct {
+		noun  fwew.Word
+		stems []string // in the order of nounNumbers, as far as the noun declines for them
+	}{
+		{fwew.Word{Navi: "tute", PartOfSpeech: "n."}, []string{"tute", "mesute", "pxesute", "aysute", "sute"}},
+		{fwew.Word{Navi: "'eylan", PartOfSpeech: "n."}, []string{"'eylan", "meylan", "pxeylan", "ayeylan", "eylan"}},
+		{fwew.Word{Navi: "kelku", PartOfSpeech: "n."}, []string{"kelku", "mehelku", "pxehelku", "ayhelku", "helku"}},
+		{fwew.Word{Navi: "ikran", PartOfSpeech: "n."}, []string{"ikran", "meikran", "pxeikran", "ayikran"}},
+		{fwew.Word{Navi: "nga", PartOfSpeech: "pn."}, []string{"nga"}},
+	}
+	for _, tt := range tests {
+		numbers, stems := numberStems(tt.noun)
+		var got []string
+		for _, number := range numbers {
+			got = append(got, stems[number])
+		}
+		if !slices.Equal(numbers, nounNumbers[:len(numbers)]) || !slices.Equal(got, tt.stems) {
+			t.Errorf("numberStems(%q) = %q %q, want %q", tt.noun.Navi, numbers, got, tt.stems)
+		}
+	}
+}
+
+// every form and alternative is searched, with the dictionary in testdata
+func TestDeclineRecognized(t *testing.T) {
+	tute := fwew.Word{ID: "5", Navi: "tute", PartOfSpeech: "n."}
+	unknown := fwew.Word{ID: "999", Navi: "tute", PartOfSpeech: "n."}
+	for _, f := range decline(tute) {
+		if !f.Recognized || len(f.UnrecognizedAlternatives) > 0 {
+			t.Errorf("%s %s %s: recognized %v, unrecognized alternatives %q", f.Number, f.Case, f.Form, f.Recognized, f.UnrecognizedAlternatives)
+		}
+	}
+	for _, f := range decline(unknown) {
+		if f.Recognized || !slices.Equal(f.UnrecognizedAlternatives, f.Alternatives) {
+			t.Errorf("%s %s %s of an unknown noun: recognized %v, unrecognized alternatives %q of %q",
+				f.Number, f.Case, f.Form, f.Recognized, f.UnrecognizedAlternatives, f.Alternatives)
+		}
+	}
+}
+
+func TestDeclensionText(t *testing.T) {
+	var d declension
+	for _, c := range nounCases {
+		d = append(d, declinedForm{Number: "singular", Case: c, Form: c[:3], Recognized: true})
+	}
+	d[2].Alternatives = []string{"pa2", "pa3"}
+	d[2].UnrecognizedAlternatives = []string{"pa3"}
+	d[3].Recognized = false
+	d[3].Alternatives = []string{"da2"}
+
+	want := "" +
+		"            singular\n" +
+		"subjective  sub\n" +
+		"agentive    age\n" +
+		"patientive  pat, pa2, pa3*\n" +
+		"dative      dat*, da2\n" +
+		"genitive    gen\n" +
+		"topical     top\n" +
+		"* not recognized by the analyser\n"
+	if got := d.text(); got != want {
+		t.Errorf("text() =\n%s\nwant\n%s", got, want)
+	}
+}
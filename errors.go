@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// error codes returned in apiError.Code
+const (
+	codeNoResults        = "no_results"
+	codeMissingParameter = "missing_parameter"
+	codeInvalidParameter = "invalid_parameter"
+	codeInvalidCombo     = "invalid_combination"
+	codeOutOfRange       = "out_of_range"
+	codeInvalidBody      = "invalid_body"
+	codeTooLarge         = "payload_too_large"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal_error"
+)
+
+// apiError is the error body returned by every endpoint.
+// Clients should switch on Code rather than on Message.
+type apiError struct {
+	Code      string `json:"code"`
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	Localized string `json:"localized,omitempty"`
+	Param     string `json:"param,omitempty"`
+	// Suggestions are close headwords for each searched word that found nothing
+	Suggestions map[string][]suggestion `json:"suggestions,omitempty"`
+	// Step is the calculation step that failed
+	Step *calcStep `json:"step,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newError(status int, code string, msg string) *apiError {
+	return &apiError{Code: code, Status: status, Message: msg}
+}
+
+// the search found nothing
+func errNoResults() *apiError {
+	return newError(http.StatusNotFound, codeNoResults, "no results")
+}
+
+// a required parameter was not given
+func errMissingParam(param string) *apiError {
+	e := newError(http.StatusBadRequest, codeMissingParameter, "missing parameter: "+param)
+	e.Param = param
+	return e
+}
+
+// a parameter was given but could not be used.
+// textKey names the fwew-lib text used as localized message, if any
+func errInvalidParam(param string, value string, expected string, textKey string) *apiError {
+	e := newError(http.StatusBadRequest, codeInvalidParameter,
+		fmt.Sprintf("invalid parameter %s: %q (expected %s)", param, value, expected))
+	e.Param = param
+	if textKey != "" {
+		e.Localized = fwew.Text(textKey)
+	}
+	return e
+}
+
+// parameters were given that cannot be used together; param names the one given last
+func errInvalidCombination(param string, msg string) *apiError {
+	e := newError(http.StatusBadRequest, codeInvalidCombo, msg)
+	e.Param = param
+	return e
+}
+
+// a calculation step gave a value with no Na'vi number word
+func errOutOfRange(step calcStep) *apiError {
+	e := newError(http.StatusBadRequest, codeOutOfRange,
+		fmt.Sprintf("%s %s %s = %d is out of range (expected 0 to %d)", step.Left, step.Operator, step.Right, step.Decimal, maxNaviNumber))
+	e.Param = "expr"
+	e.Step = &step
+	e.Localized = fwew.Text("invalidIntError")
+	return e
+}
+
+// the request body could not be decoded
+func errInvalidBody(msg string) *apiError {
+	return newError(http.StatusBadRequest, codeInvalidBody, msg)
+}
+
+// the request asked for too much at once
+func errTooLarge(msg string) *apiError {
+	return newError(http.StatusRequestEntityTooLarge, codeTooLarge, msg)
+}
+
+// the client sent too many requests; Retry-After says when to try again
+func errRateLimited() *apiError {
+	return newError(http.StatusTooManyRequests, codeRateLimited, "too many requests")
+}
+
+// something failed on our side; the cause is logged, not returned
+func errInternal(err error) *apiError {
+	log.Printf("internal error: %v", err)
+	return newError(http.StatusInternalServerError, codeInternal, "internal error")
+}
+
+// write e as the response
+func writeError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// Respond to a search that found nothing.
+// By default this is a no_results error with status 404. With ?noresults=empty
+// (or "NoResults": "empty" in config.json) it is status 200 with an empty array instead.
+func writeNoResults(w http.ResponseWriter, r *http.Request) {
+	writeNoResultsError(w, r, errNoResults())
+}
+
+// Same as writeNoResults, with a no_results error carrying more details
+func writeNoResultsError(w http.ResponseWriter, r *http.Request, e *apiError) {
+	setResultCount(r, 0)
+	mode := r.URL.Query().Get("noresults")
+	if mode == "" {
+		mode = config.NoResults
+	}
+
+	if mode == "empty" {
+		render(w, r, []any{})
+		return
+	}
+
+	writeError(w, e)
+}
+
+// answer requests to unknown routes
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, newError(http.StatusNotFound, codeNotFound, "no such endpoint: "+r.URL.Path))
+}
+
+// answer requests to known routes with the wrong method
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, newError(http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed: "+r.Method))
+}
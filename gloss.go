@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// maximum size of a sentence to gloss
+const glossMaxBody = 64 << 10
+
+// glossRequest is the JSON form of a sentence to gloss.
+type glossRequest struct {
+	Text string `json:"text"`
+}
+
+// glossToken is one word, multi-word entry or punctuation mark of a glossed sentence.
+type glossToken struct {
+	Text        string      `json:"text"`
+	Punctuation bool        `json:"punctuation,omitempty"`
+	Segmented   string      `json:"segmented,omitempty"` // the text split into the stem and affixes of the first candidate
+	Gloss       string      `json:"gloss,omitempty"`     // the first candidate glossed morpheme by morpheme, e.g. "PL-rock-ACC"
+	Words       []fwew.Word `json:"words,omitempty"`     // all candidates, with their affixes
+}
+
+// glossResult is an interlinear gloss of a sentence.
+type glossResult struct {
+	Text      string       `json:"text"`
+	Tokens    []glossToken `json:"tokens"`
+	Segmented string       `json:"segmented"` // e.g. "oe-l ayskxe-ti t<ìrm>aw"
+	Gloss     string       `json:"gloss"`
+}
+
+// Lines of the original text, segmented forms and glosses, aligned word by word
+func (g glossResult) text() string {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	var line [3][]string
+	for _, token := range g.Tokens {
+		segmented, gloss := token.Segmented, token.Gloss
+		if token.Punctuation {
+			segmented, gloss = token.Text, ""
+		} else if segmented == "" {
+			segmented, gloss = token.Text, "?"
+		}
+		line[0] = append(line[0], token.Text)
+		line[1] = append(line[1], segmented)
+		line[2] = append(line[2], gloss)
+	}
+	for _, l := range line {
+		tw.Write([]byte(strings.Join(l, "\t") + "\n"))
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// Gloss a Na'vi sentence, POSTed as plain text or as a JSON glossRequest.
+// Definitions are given in the lang path variable.
+func glossSentence(w http.ResponseWriter, r *http.Request) {
+	lang := mux.Vars(r)["lang"]
+	if e := checkLang(lang); e != nil {
+		writeError(w, e)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, glossMaxBody)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errTooLarge("sentence too large"))
+		return
+	}
+	text := string(body)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var req glossRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, errInvalidBody(`invalid body: expected {"text": "..."} or plain text`))
+			return
+		}
+		text = req.Text
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		writeError(w, errMissingParam("text"))
+		return
+	}
+
+	result := gloss(text, lang)
+	setResultCount(r, len(result.Tokens))
+	render(w, r, result)
+}
+
+// gloss text with definitions in lang
+func gloss(text string, lang string) glossResult {
+	result := glossResult{Text: text}
+	var segmented, glosses []string
+
+	for _, token := range groupMultiwords(tokenize(text), fwew.GetMultiwordWords()) {
+		if !token.Punctuation {
+			token.Words = lookUp(token.Text)
+			if len(token.Words) > 0 {
+				token.Segmented, token.Gloss = segment(token.Words[0], token.Text, leipzigGloss(localDefinition(token.Words[0], lang)))
+			}
+		}
+		result.Tokens = append(result.Tokens, token)
+
+		switch {
+		case token.Punctuation:
+			segmented = append(segmented, token.Text)
+		case token.Segmented == "":
+			segmented = append(segmented, token.Text)
+			glosses = append(glosses, "?")
+		default:
+			segmented = append(segmented, token.Segmented)
+			glosses = append(glosses, token.Gloss)
+		}
+	}
+
+	result.Segmented = strings.Join(segmented, " ")
+	result.Gloss = strings.Join(glosses, " ")
+	return result
+}
+
+// Split text into words and punctuation marks.
+// Apostrophes belong to words, since ' is a Na'vi consonant.
+func tokenize(text string) []glossToken {
+	var tokens []glossToken
+	var current []rune
+	punctuation := false
+
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, glossToken{Text: string(current), Punctuation: punctuation})
+			current = nil
+		}
+	}
+
+	runes := []rune(text)
+	for i, c := range runes {
+		// hyphens inside words, as in "tì-", are part of them
+		isWord := unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c) ||
+			c == '\'' || c == '’' || c == '‘' ||
+			(c == '-' && len(current) > 0 && !punctuation && i+1 < len(runes) && unicode.IsLetter(runes[i+1]))
+		switch {
+		case unicode.IsSpace(c):
+			flush()
+		case isWord:
+			if punctuation {
+				flush()
+			}
+			punctuation = false
+			current = append(current, c)
+		default:
+			if !punctuation {
+				flush()
+			}
+			punctuation = true
+			current = append(current, c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Join the words of multi-word entries such as "tìkangkem si" into one token.
+// multiwords maps the first word of every entry to the words that follow, as fwew-lib has them.
+func groupMultiwords(tokens []glossToken, multiwords map[string][][]string) []glossToken {
+	var grouped []glossToken
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if !token.Punctuation {
+			longest := 0
+			for _, rest := range multiwords[strings.ToLower(token.Text)] {
+				if len(rest) > longest && wordsMatch(tokens[i+1:], rest) {
+					longest = len(rest)
+				}
+			}
+			for _, next := range tokens[i+1 : i+1+longest] {
+				token.Text += " " + next.Text
+			}
+			i += longest
+		}
+		grouped = append(grouped, token)
+	}
+	return grouped
+}
+
+// whether tokens start with the words of rest
+func wordsMatch(tokens []glossToken, rest []string) bool {
+	if len(tokens) < len(rest) {
+		return false
+	}
+	for i, word := range rest {
+		if tokens[i].Punctuation || !strings.EqualFold(tokens[i].Text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// all dictionary entries text could be, with affixes
+func lookUp(text string) []fwew.Word {
+	groups, err := fwew.TranslateFromNaviHash(text, true, false, false)
+	if err != nil {
+		return nil
+	}
+
+	var words []fwew.Word
+	for _, group := range groups {
+		for _, word := range group {
+			if word.ID != "" {
+				words = append(words, word)
+			}
+		}
+	}
+
+	// a multi-word token is one entry; drop the entries of its single words
+	if strings.Contains(text, " ") {
+		multi := slices.DeleteFunc(slices.Clone(words), func(word fwew.Word) bool {
+			return !strings.Contains(word.Navi, " ")
+		})
+		if len(multi) > 0 {
+			return multi
+		}
+	}
+	return words
+}
+
+// Split surface, the form of word found in a text, into its prefixes, stem and suffixes
+// as the Leipzig rules have it, and gloss it morpheme by morpheme with the functions of
+// the affix catalogue and stemGloss: "ay-skxe-ti" is "PL-rock-ACC". Infixes are marked
+// with angle brackets in both, "t<ìrm>aw" is "<PST.REC.IPFV>hunt". Affixes that do not
+// show in surface, such as a plural marked by lenition alone, follow the stem gloss
+// after a backslash: "skxe-ti" is "rock\PL-ACC".
+func segment(word fwew.Word, surface, stemGloss string) (segmented, glossed string) {
+	rest := []rune(strings.NewReplacer("’", "'", "‘", "'").Replace(surface))
+	var forms, glosses, hidden []string
+
+	for _, prefix := range word.Affixes.Prefix {
+		n := len([]rune(prefix))
+		if n < len(rest) && strings.EqualFold(string(rest[:n]), prefix) {
+			forms = append(forms, string(rest[:n]))
+			glosses = append(glosses, affixGloss("prefix", prefix))
+			rest = rest[n:]
+		} else {
+			hidden = append(hidden, affixGloss("prefix", prefix))
+		}
+	}
+
+	var suffixForms, suffixGlosses []string
+	for i := len(word.Affixes.Suffix) - 1; i >= 0; i-- {
+		suffix := word.Affixes.Suffix[i]
+		n := len([]rune(suffix))
+		if n < len(rest) && strings.EqualFold(string(rest[len(rest)-n:]), suffix) {
+			suffixForms = slices.Insert(suffixForms, 0, string(rest[len(rest)-n:]))
+			suffixGlosses = slices.Insert(suffixGlosses, 0, affixGloss("suffix", suffix))
+			rest = rest[:len(rest)-n]
+		} else {
+			hidden = append(hidden, affixGloss("suffix", suffix))
+		}
+	}
+
+	stem, slots, marked := markInfixes(word, string(rest))
+	var before, after string
+	for position, slot := range slots {
+		for _, infix := range slot {
+			switch {
+			case !marked:
+				hidden = append(hidden, affixGloss("infix", infix))
+			case position == 2:
+				after += "<" + affixGloss("infix", infix) + ">"
+			default:
+				before += "<" + affixGloss("infix", infix) + ">"
+			}
+		}
+	}
+	stemGloss = before + firstOf(stemGloss, "?") + after
+	for _, h := range hidden {
+		stemGloss += "\\" + h
+	}
+
+	forms = append(append(forms, stem), suffixForms...)
+	glosses = append(append(glosses, stemGloss), suffixGlosses...)
+	return strings.Join(forms, "-"), strings.Join(glosses, "-")
+}
+
+// Put the infixes of word into stem, the surface form of its infixed stem, in angle brackets,
+// e.g. "t<ìrm>aw". Also returns the infixes by infix position, and false if stem does not
+// have the letters of the infixed word, in which case it is returned as is.
+func markInfixes(word fwew.Word, stem string) (string, [3][]string, bool) {
+	var slots [3][]string
+	for _, infix := range word.Affixes.Infix {
+		position := infixPosition(infix)
+		slots[position] = append(slots[position], infix)
+	}
+	if len(word.Affixes.Infix) == 0 {
+		return stem, slots, true
+	}
+	if word.InfixLocations == "" || word.InfixLocations == "NULL" {
+		return stem, slots, false
+	}
+
+	infixed := applyLenitions(word.InfixLocations, word.Affixes.Lenition)
+	for i, slot := range slots {
+		marker := "<" + string(rune('0'+i)) + ">"
+		if len(slot) > 0 {
+			infixed = strings.Replace(infixed, marker, "<"+strings.Join(slot, "")+">", 1)
+		} else {
+			infixed = strings.Replace(infixed, marker, "", 1)
+		}
+	}
+
+	// the letters of stem, keeping their case, with the brackets of infixed
+	var b strings.Builder
+	letters := []rune(stem)
+	for _, c := range infixed {
+		if c == '<' || c == '>' {
+			b.WriteRune(c)
+			continue
+		}
+		if len(letters) == 0 || !strings.EqualFold(string(letters[0]), string(c)) {
+			return stem, slots, false
+		}
+		b.WriteRune(letters[0])
+		letters = letters[1:]
+	}
+	if len(letters) > 0 {
+		return stem, slots, false
+	}
+	return b.String(), slots, true
+}
+
+// s with the lenitions fwew-lib found applied to its first letter, e.g. "ts→s"
+func applyLenitions(s string, lenitions []string) string {
+	for _, lenition := range lenitions {
+		from, to, ok := strings.Cut(lenition, "→")
+		if ok && strings.HasPrefix(strings.ToLower(s), from) {
+			return to + s[len(from):]
+		}
+	}
+	return s
+}
+
+// the function of an affix in the affix catalogue, e.g. "ACC", or the affix itself if it has none
+func affixGloss(kind, affix string) string {
+	if info, ok := affixCatalogue[kind+":"+strings.ToLower(affix)]; ok {
+		return info.Function
+	}
+	return affix
+}
+
+// The first gloss of a definition, with its words joined by periods as the
+// Leipzig rules have it, e.g. "to be, exist" -> "to.be"
+func leipzigGloss(definition string) string {
+	first := glosses(definition)
+	if len(first) == 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(first[0]), ".")
+}
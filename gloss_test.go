@@ -0,0 +1,132 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// the texts of tokens, punctuation marks in brackets
+func tokenTexts(tokens []glossToken) []string {
+	var texts []string
+	for _, token := range tokens {
+		if token.Punctuation {
+			texts = append(texts, "["+token.Text+"]")
+		} else {
+			texts = append(texts, token.Text)
+		}
+	}
+	return texts
+}
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"Oel ngati kameie.":       {"Oel", "ngati", "kameie", "[.]"},
+		"Kaltxì, ma 'eylan!":      {"Kaltxì", "[,]", "ma", "'eylan", "[!]"},
+		"ma ’eylan":               {"ma", "’eylan"},
+		"tì-kangkem si":           {"tì-kangkem", "si"},
+		"a - b":                   {"a", "[-]", "b"},
+		"tute...":                 {"tute", "[...]"},
+		"\"Srane\", pamrel soli.": {"[\"]", "Srane", "[\",]", "pamrel", "soli", "[.]"},
+		"":                        nil,
+	}
+	for text, want := range tests {
+		if got := tokenTexts(tokenize(text)); !slices.Equal(got, want) {
+			t.Errorf("tokenize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestGroupMultiwords(t *testing.T) {
+	multiwords := map[string][][]string{
+		"tìkangkem": {{"si"}},
+		"tsaw":      {{"nìwotx"}},
+		"lu":        {{"hasey"}, {"hasey", "nìwotx"}},
+	}
+	tests := map[string][]string{
+		"Oel tìkangkem si.":        {"Oel", "tìkangkem si", "[.]"},
+		"Tìkangkem Si":             {"Tìkangkem Si"},
+		"tìkangkem, si":            {"tìkangkem", "[,]", "si"},
+		"lu hasey nìwotx":          {"lu hasey nìwotx"}, // the longest entry wins
+		"lu hasey":                 {"lu hasey"},
+		"lu":                       {"lu"},
+		"tsaw tìkangkem si nìwotx": {"tsaw", "tìkangkem si", "nìwotx"},
+	}
+	for text, want := range tests {
+		if got := tokenTexts(groupMultiwords(tokenize(text), multiwords)); !slices.Equal(got, want) {
+			t.Errorf("groupMultiwords(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	affixed := func(navi, locations string, prefixes, infixes, suffixes, lenitions []string) fwew.Word {
+		word := fwew.Word{Navi: navi, InfixLocations: locations}
+		word.Affixes.Prefix = prefixes
+		word.Affixes.Infix = infixes
+		word.Affixes.Suffix = suffixes
+		word.Affixes.Lenition = lenitions
+		return word
+	}
+	tests := []struct {
+		word               fwew.Word
+		surface, stemGloss string
+		segmented, gloss   string
+	}{
+		{affixed("oe", "NULL", nil, nil, []string{"l"}, nil), "Oel", "I", "Oe-l", "I-ERG"},
+		{affixed("tskxe", "NULL", []string{"ay"}, nil, []string{"ti"}, []string{"ts→s"}), "ayskxeti", "rock", "ay-skxe-ti", "PL-rock-ACC"},
+		{affixed("tskxe", "NULL", []string{"ay"}, nil, []string{"ti"}, []string{"ts→s"}), "skxeti", "rock", "skxe-ti", `rock\PL-ACC`},
+		{affixed("'eylan", "NULL", []string{"me"}, nil, nil, []string{"'→"}), "meylan", "friend", "me-ylan", "DU-friend"},
+		{affixed("'eylan", "NULL", nil, nil, nil, nil), "’eylan", "friend", "'eylan", "friend"},
+		{affixed("taw", "t<0><1>aw<2>", nil, []string{"ìrm"}, nil, nil), "tìrmaw", "sky", "t<ìrm>aw", "<PST.REC.IPFV>sky"},
+		{affixed("kame", "k<0><1>am<2>e", nil, []string{"ei"}, nil, nil), "kameie", "see", "kam<ei>e", "see<LAUD>"},
+		{affixed("taron", "t<0><1>ar<2>on", nil, []string{"eyk", "am"}, []string{"ti"}, nil), "teykamaronti", "hunt", "t<eyk><am>aron-ti", "<CAUS><PST>hunt-ACC"},
+		{affixed("taron", "t<0><1>ar<2>on", nil, []string{"ap"}, nil, nil), "taparon", "hunt", "t<ap>aron", "<REFL>hunt"},
+		{affixed("taron", "t<0><1>ar<2>on", nil, []string{"am"}, nil, nil), "tamaronn", "hunt", "tamaronn", `hunt\PST`},
+		{affixed("taron", "t<0><1>ar<2>on", nil, nil, nil, nil), "taron", "", "taron", "?"},
+		{affixed("oe", "NULL", nil, nil, []string{"zzz"}, nil), "oezzz", "I", "oe-zzz", "I-zzz"},
+	}
+	for _, tt := range tests {
+		segmented, gloss := segment(tt.word, tt.surface, tt.stemGloss)
+		if segmented != tt.segmented || gloss != tt.gloss {
+			t.Errorf("segment(%s %+v, %q) = %q %q, want %q %q", tt.word.Navi, tt.word.Affixes, tt.surface,
+				segmented, gloss, tt.segmented, tt.gloss)
+		}
+	}
+}
+
+func TestInfixPosition(t *testing.T) {
+	tests := map[string]int{"äp": 0, "ap": 0, "ep": 0, "epeyk": 0, "eyk": 0, "am": 1, "us": 1, "ìyev": 1, "ats": 2, "eiy": 2, "ang": 2, "uy": 2}
+	for infix, want := range tests {
+		if got := infixPosition(infix); got != want {
+			t.Errorf("infixPosition(%q) = %d, want %d", infix, got, want)
+		}
+	}
+}
+
+func TestLeipzigGloss(t *testing.T) {
+	tests := map[string]string{
+		"to be, exist": "to.be",
+		"hunt":         "hunt",
+		"I, me":        "I",
+		"NULL":         "",
+		"":             "",
+	}
+	for definition, want := range tests {
+		if got := leipzigGloss(definition); got != want {
+			t.Errorf("leipzigGloss(%q) = %q, want %q", definition, got, want)
+		}
+	}
+}
+
+// with the dictionary in testdata
+func TestGloss(t *testing.T) {
+	g := gloss("Oel ayikranti tamaron, mehelku.", "en")
+	if want := "Oe-l ay-ikran-ti t<am>aron , me-helku ."; g.Segmented != want {
+		t.Errorf("segmented %q, want %q", g.Segmented, want)
+	}
+	if want := "I-ERG PL-banshee-ACC <PST>hunt DU-home"; g.Gloss != want {
+		t.Errorf("gloss %q, want %q", g.Gloss, want)
+	}
+}
@@ -5,11 +5,15 @@ go 1.25.0
 require (
 	github.com/fwew/fwew-lib/v5 v5.28.1
 	github.com/gorilla/mux v1.8.1
+	golang.org/x/crypto v0.54.0
+	golang.org/x/text v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	filippo.io/edwards25519 v1.1.1 // indirect
 	github.com/go-sql-driver/mysql v1.8.1 // indirect
+	golang.org/x/net v0.56.0 // indirect
 )
 
 //for testing on a local machine's fwew-lib
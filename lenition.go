@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// lenitionRule softens one initial consonant.
+type lenitionRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// the text of a rule, e.g. "px→p", as in the affix catalogue
+func (rule lenitionRule) String() string {
+	return rule.From + "→" + rule.To
+}
+
+// the lenition rules, in the order of the lenition table
+var lenitionRules = []lenitionRule{
+	{"kx", "k"}, {"px", "p"}, {"tx", "t"}, {"k", "h"}, {"p", "f"}, {"t", "s"}, {"ts", "s"}, {"'", ""},
+}
+
+// what becomes of ' in the lenition table, by language
+var lenitionDisappears = map[string]string{
+	"de": "(entfällt, außer vor ll oder rr)",
+	"en": "(disappears, except before ll or rr)",
+	"es": "(desaparece, excepto antes de ll o rr)",
+	"et": "(kaob, välja arvatud ll või rr ees)",
+	"fr": "(disparaît, sauf devant ll ou rr)",
+	"hu": "(eltűnik, kivéve ll vagy rr előtt)",
+	"it": "(scompare, tranne prima di ll o rr)",
+	"ko": "(ll 또는 rr 앞이 아니면 사라짐)",
+	"nl": "(verdwijnt, behalve vóór ll of rr)",
+	"pl": "(znika, z wyjątkiem przed ll lub rr)",
+	"pt": "(desaparece, exceto antes de ll ou rr)",
+	"ru": "(исчезает, кроме как перед ll или rr)",
+	"sv": "(försvinner, utom före ll eller rr)",
+	"tr": "(ll veya rr öncesi hariç kaybolur)",
+	"uk": "(зникає, крім як перед ll або rr)",
+}
+
+// lenition is the result of leniting one word.
+type lenition struct {
+	Word    string        `json:"word"`
+	Lenited string        `json:"lenited"`
+	Rule    *lenitionRule `json:"rule"`           // null if the word does not lenite
+	Note    string        `json:"note,omitempty"` // why it does not
+}
+
+// lenitionSource is a word that lenites to the searched one.
+type lenitionSource struct {
+	Source string        `json:"source"`
+	Rule   *lenitionRule `json:"rule"` // null if the searched word is its own source
+	Exists bool          `json:"exists"`
+	IDs    []string      `json:"ids,omitempty"` // of the dictionary entries spelled Source
+}
+
+// The lenited form of word and the rule applied, or nil if its first letter does not lenite.
+// ' disappears, except before ll or rr.
+func lenite(word string) (string, *lenitionRule) {
+	normalized := strings.NewReplacer("’", "'", "‘", "'").Replace(word)
+	var match *lenitionRule
+	for i, rule := range lenitionRules {
+		if strings.HasPrefix(strings.ToLower(normalized), rule.From) && (match == nil || len(rule.From) > len(match.From)) {
+			match = &lenitionRules[i]
+		}
+	}
+	if match == nil {
+		return word, nil
+	}
+	rest := normalized[len(match.From):]
+	if match.From == "'" && (strings.HasPrefix(rest, "ll") || strings.HasPrefix(rest, "rr")) {
+		return word, nil
+	}
+	return match.To + rest, match
+}
+
+// Every word that lenites to word, with the rule undoing which gives it.
+// Word is its own source if its first letter does not lenite.
+func unlenite(word string) []lenitionSource {
+	var sources []lenitionSource
+	if lenited, rule := lenite(word); rule == nil && lenited == word {
+		sources = append(sources, lenitionSource{Source: word})
+	}
+	for i, rule := range lenitionRules {
+		if !strings.HasPrefix(word, rule.To) {
+			continue
+		}
+		// ' only stands before a vowel, or before ll or rr where it stays
+		if rule.From == "'" && (word == "" || !isVowel(naviUnits(word)[0])) {
+			continue
+		}
+		source := rule.From + word[len(rule.To):]
+		// the source must lenite back by the same rule: t + xa is txa, which lenites to ta, not sxa
+		if lenited, applied := lenite(source); applied == nil || lenited != word {
+			continue
+		}
+		sources = append(sources, lenitionSource{Source: source, Rule: &lenitionRules[i]})
+	}
+	return sources
+}
+
+// Lenite a Na'vi word and tell which rule applied
+func getLenition(w http.ResponseWriter, r *http.Request) {
+	word := normalizeLenition(mux.Vars(r)["word"])
+	lenited, rule := lenite(word)
+	result := lenition{Word: word, Lenited: lenited, Rule: rule}
+	if rule == nil {
+		result.Note = "the first letter does not lenite"
+		if strings.HasPrefix(word, "'ll") || strings.HasPrefix(word, "'rr") {
+			result.Note = "' does not disappear before ll or rr"
+		}
+	}
+	render(w, r, result)
+}
+
+// List the words a lenited Na'vi word may come from, and which of them are in the dictionary
+func getLenitionSources(w http.ResponseWriter, r *http.Request) {
+	sources := unlenite(normalizeLenition(mux.Vars(r)["word"]))
+	for i, source := range sources {
+		words, _ := headwords(source.Source, nil)
+		for _, word := range words {
+			sources[i].IDs = append(sources[i].IDs, word.ID)
+		}
+		sources[i].Exists = len(sources[i].IDs) > 0
+	}
+	setResultCount(r, len(sources))
+	render(w, r, sources)
+}
+
+// lower case, with typographic apostrophes as '
+func normalizeLenition(word string) string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	return strings.NewReplacer("’", "'", "‘", "'").Replace(word)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLenite(t *testing.T) {
+	tests := []struct {
+		word    string
+		lenited string
+		rule    string // "" if the word does not lenite
+	}{
+		{"kxetse", "ketse", "kx→k"},
+		{"pxen", "pen", "px→p"},
+		{"txon", "ton", "tx→t"},
+		{"kelku", "helku", "k→h"},
+		{"pa'li", "fa'li", "p→f"},
+		{"tute", "sute", "t→s"},
+		{"tsmukan", "smukan", "ts→s"},
+		{"'eylan", "eylan", "'→"},
+		{"’eylan", "eylan", "'→"},
+		{"'rrta", "'rrta", ""},
+		{"'llngo", "'llngo", ""},
+		{"ikran", "ikran", ""},
+		{"skxawng", "skxawng", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		lenited, rule := lenite(tt.word)
+		got := ""
+		if rule != nil {
+			got = rule.String()
+		}
+		if lenited != tt.lenited || got != tt.rule {
+			t.Errorf("lenite(%q) = %q %q, want %q %q", tt.word, lenited, got, tt.lenited, tt.rule)
+		}
+	}
+}
+
+func TestUnlenite(t *testing.T) {
+	tests := []struct {
+		word    string
+		sources []string
+	}{
+		{"helku", []string{"helku", "kelku"}},
+		{"ketse", []string{"kxetse"}},
+		{"sute", []string{"sute", "tute", "tsute"}},
+		{"ta", []string{"txa"}},
+		{"eylan", []string{"eylan", "'eylan"}},
+		{"'rrta", []string{"'rrta"}},
+		{"fa'li", []string{"fa'li", "pa'li"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, source := range unlenite(tt.word) {
+			got = append(got, source.Source)
+			// every source but the word itself lenites back to it
+			if source.Rule == nil && source.Source != tt.word {
+				t.Errorf("unlenite(%q): source %q has no rule", tt.word, source.Source)
+			}
+			if lenited, _ := lenite(source.Source); source.Rule != nil && lenited != tt.word {
+				t.Errorf("unlenite(%q): source %q lenites to %q", tt.word, source.Source, lenited)
+			}
+		}
+		if !slices.Equal(got, tt.sources) {
+			t.Errorf("unlenite(%q) = %q, want %q", tt.word, got, tt.sources)
+		}
+	}
+}
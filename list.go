@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// sort orders accepted by the list endpoints
+var listSorts = []string{"alpha", "id", "syllables", "pos", "none"}
+
+// listOptions describes which part of a word list to return and how.
+type listOptions struct {
+	Offset int
+	Limit  int      // 0 means no limit
+	Fields []string // Word field names; empty means all fields
+	Sort   string
+}
+
+// read listOptions from the query string:
+//
+//	offset  number of words to skip (default 0)
+//	limit   maximum number of words to return (default all)
+//	fields  comma-separated Word fields to return, e.g. Navi,IPA,EN (default all)
+//	sort    alpha, id, syllables, pos or none (default defaultSort)
+func parseListQuery(r *http.Request, defaultSort string) (opts listOptions, e *apiError) {
+	query := r.URL.Query()
+
+	opts.Offset, e = nonNegativeParam(query, "offset")
+	if e != nil {
+		return
+	}
+
+	opts.Limit, e = nonNegativeParam(query, "limit")
+	if e != nil {
+		return
+	}
+
+	if fields := query.Get("fields"); fields != "" {
+		for _, f := range strings.Split(fields, ",") {
+			name, ok := wordField(strings.TrimSpace(f))
+			if !ok {
+				return opts, errInvalidParam("fields", f, "comma-separated Word field names", "")
+			}
+			opts.Fields = append(opts.Fields, name)
+		}
+	}
+
+	opts.Sort = query.Get("sort")
+	if opts.Sort == "" {
+		opts.Sort = defaultSort
+	}
+	if !slices.Contains(listSorts, opts.Sort) {
+		return opts, errInvalidParam("sort", opts.Sort, strings.Join(listSorts, ", "), "")
+	}
+
+	return opts, nil
+}
+
+// parse an optional non-negative integer query parameter
+func nonNegativeParam(query url.Values, name string) (int, *apiError) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errInvalidParam(name, value, "a non-negative integer", "invalidDecimalError")
+	}
+	return n, nil
+}
+
+// the exported name of the Word field called name, ignoring case
+func wordField(name string) (string, bool) {
+	t := reflect.TypeOf(fwew.Word{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(t.Field(i).Name, name) {
+			return t.Field(i).Name, true
+		}
+	}
+	return "", false
+}
+
+// Sort words in place
+func sortWords(words []fwew.Word, order string) {
+	switch order {
+	case "alpha":
+		slices.SortStableFunc(words, compareAlpha)
+	case "id":
+		slices.SortStableFunc(words, func(a, b fwew.Word) int {
+			a1, _ := strconv.Atoi(a.ID)
+			b1, _ := strconv.Atoi(b.ID)
+			return a1 - b1
+		})
+	case "syllables":
+		slices.SortStableFunc(words, func(a, b fwew.Word) int {
+			if d := syllableCount(a) - syllableCount(b); d != 0 {
+				return d
+			}
+			return compareAlpha(a, b)
+		})
+	case "pos":
+		slices.SortStableFunc(words, func(a, b fwew.Word) int {
+			if d := strings.Compare(a.PartOfSpeech, b.PartOfSpeech); d != 0 {
+				return d
+			}
+			return compareAlpha(a, b)
+		})
+	}
+}
+
+// compare two words in Na'vi alphabetical order
+func compareAlpha(a, b fwew.Word) int {
+	if fwew.AlphabetizeHelper(a.Navi, b.Navi) {
+		return -1
+	}
+	if fwew.AlphabetizeHelper(b.Navi, a.Navi) {
+		return 1
+	}
+	return 0
+}
+
+// number of syllables of a word, counting every part of multi-word entries
+func syllableCount(word fwew.Word) int {
+	return len(strings.FieldsFunc(word.Syllables, func(r rune) bool {
+		return r == '-' || r == ' '
+	}))
+}
+
+// keep only the given fields of each word
+func projectWords(words []fwew.Word, fields []string) []map[string]any {
+	projected := make([]map[string]any, len(words))
+	for i, word := range words {
+		v := reflect.ValueOf(word)
+		m := make(map[string]any, len(fields))
+		for _, f := range fields {
+			m[f] = v.FieldByName(f).Interface()
+		}
+		projected[i] = m
+	}
+	return projected
+}
+
+// Write one page of a word list.
+// Sets X-Total-Count to the number of words before paging, and a Link header
+// pointing at the next and previous pages when paging.
+func writeWordList(w http.ResponseWriter, r *http.Request, words []fwew.Word, defaultSort string) {
+	opts, e := parseListQuery(r, defaultSort)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	if len(words) == 0 {
+		writeNoResults(w, r)
+		return
+	}
+
+	// words may be fwew-lib's own cache, so never sort it in place
+	words = slices.Clone(words)
+	sortWords(words, opts.Sort)
+
+	total := len(words)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	start := min(opts.Offset, total)
+	end := total
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, total)
+		var links []string
+		if end < total {
+			links = append(links, pageLink(r, end, opts.Limit, "next"))
+		}
+		if start > 0 {
+			links = append(links, pageLink(r, max(start-opts.Limit, 0), opts.Limit, "prev"))
+		}
+		if len(links) > 0 {
+			w.Header().Set("Link", strings.Join(links, ", "))
+		}
+	}
+	page := words[start:end]
+	setResultCount(r, len(page))
+
+	if len(opts.Fields) > 0 {
+		render(w, r, projectWords(page, opts.Fields))
+		return
+	}
+
+	render(w, r, page)
+}
+
+// a Link header entry for the page starting at offset
+func pageLink(r *http.Request, offset int, limit int, rel string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = query.Encode()
+	return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+}
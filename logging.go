@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// path variables and query parameters that never hold text typed in by users.
+// When LogRedactQueries is set, every other parameter is logged as "[redacted]",
+// so that parameters added later are redacted until listed here.
+var plainParams = map[string]bool{
+	"lang": true, "n": true, "s": true, "s1": true, "s2": true, "s3": true, "c": true, "num": true,
+	"dialect": true, "ending": true, "nm": true, "am": true, "strict": true, "affixes": true, "dir": true, "shape": true,
+	"format": true, "fields": true, "columns": true, "noresults": true, "offset": true, "limit": true, "sort": true,
+	"rank": true, "explain": true, "max": true, "seed": true, "withseed": true, "from": true, "to": true,
+	"tense": true, "aspect": true, "mood": true, "intent": true, "participle": true,
+	"evidential": true, "affect": true, "formal": true, "causative": true, "reflexive": true,
+}
+
+// requestInfo is shared by the middlewares and handlers of one request.
+type requestInfo struct {
+	id      string
+	results int // number of results the handler returned, -1 if not applicable
+}
+
+type requestInfoKey struct{}
+
+// the requestInfo of r, or a throwaway one outside of the middleware
+func infoOf(r *http.Request) *requestInfo {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{results: -1}
+}
+
+// record how many results a handler returned, for the access log
+func setResultCount(r *http.Request, n int) {
+	infoOf(r).results = n
+}
+
+// set up the default slog logger according to config; log.Print output goes through it as well
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+}
+
+// a client-supplied request ID is kept if it is short and printable
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Assign every request an X-Request-ID (keeping the client's, if any),
+// echo it in the response and write one structured access log line per request.
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &requestInfo{id: r.Header.Get("X-Request-ID"), results: -1}
+		if !validRequestID(info.id) {
+			info.id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", info.id)
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", info.id),
+			slog.String("method", r.Method),
+			slog.String("route", routeTemplate(r)),
+			slog.Int("status", sw.status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", sw.bytes),
+		}
+		if !config.LogRedactQueries {
+			attrs = append(attrs, slog.String("path", r.URL.Path))
+		}
+		if vars := loggedParams(r); len(vars) > 0 {
+			attrs = append(attrs, slog.Any("params", vars))
+		}
+		if info.results >= 0 {
+			attrs = append(attrs, slog.Int("results", info.results))
+		}
+
+		level := slog.LevelInfo
+		if sw.status >= 500 {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// the decoded path variables and query parameters of r, with user text redacted if configured
+func loggedParams(r *http.Request) map[string]string {
+	params := map[string]string{}
+	for name, value := range mux.Vars(r) {
+		params[name] = value
+	}
+	for name, values := range r.URL.Query() {
+		params[name] = strings.Join(values, ",")
+	}
+	if config.LogRedactQueries {
+		for name := range params {
+			if !plainParams[name] {
+				params[name] = "[redacted]"
+			}
+		}
+	}
+	return params
+}
@@ -1,14 +1,23 @@
+// fwew-lib's name generators only draw from the global math/rand source, which
+// rand.Seed no longer seeds since Go 1.24 unless randseednop=0. Seeded names need it,
+// see randomMu in seed.go for how the global source is kept to them.
+//go:debug randseednop=0
+
 package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sort"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	fwew "github.com/fwew/fwew-lib/v5"
 	"github.com/gorilla/mux"
@@ -17,6 +26,10 @@ import (
 // global instance of Config
 var config Config
 
+// dictLock guards the dictionary cache: handlers hold it for reading while
+// they search, and update holds it for writing while the dictionary reloads
+var dictLock sync.RWMutex
+
 // global configured instance of Version
 var version = Version{
 	APIVersion:  "1.7.1",
@@ -28,6 +41,60 @@ var version = Version{
 type Config struct {
 	Port    string `json:"Port"`
 	WebRoot string `json:"WebRoot"`
+	// AdminToken is the bearer token accepted by /api/update
+	AdminToken string `json:"AdminToken"`
+	// AdminSecret is the HMAC-SHA256 key accepted by /api/update
+	AdminSecret string `json:"AdminSecret"`
+	// BatchMaxSize is the maximum number of queries in one /api/batch/fwew request
+	BatchMaxSize int `json:"BatchMaxSize"`
+	// BatchConcurrency is the maximum number of queries of one batch searched at once
+	BatchConcurrency int `json:"BatchConcurrency"`
+	// NoResults is "error" to answer empty searches with 404, or "empty" to answer with 200 and []
+	NoResults string `json:"NoResults"`
+	// CacheMaxBytes is the size of the response cache; negative disables it
+	CacheMaxBytes int64 `json:"CacheMaxBytes"`
+	// CacheMaxAge is the Cache-Control max-age in seconds of cacheable responses
+	CacheMaxAge int `json:"CacheMaxAge"`
+	// http.Server limits, durations are strings such as "15s"
+	ReadHeaderTimeout duration `json:"ReadHeaderTimeout"`
+	ReadTimeout       duration `json:"ReadTimeout"`
+	WriteTimeout      duration `json:"WriteTimeout"`
+	IdleTimeout       duration `json:"IdleTimeout"`
+	MaxHeaderBytes    int      `json:"MaxHeaderBytes"`
+	// ShutdownGracePeriod is how long in-flight requests may take after SIGINT or SIGTERM
+	ShutdownGracePeriod duration `json:"ShutdownGracePeriod"`
+	// TLSCertFile and TLSKeyFile serve HTTPS with a fixed certificate
+	TLSCertFile string `json:"TLSCertFile"`
+	TLSKeyFile  string `json:"TLSKeyFile"`
+	// TLSCertDir serves HTTPS with certificates obtained automatically for TLSHosts
+	// and cached in this directory (autocert.DirCache format)
+	TLSCertDir string   `json:"TLSCertDir"`
+	TLSHosts   []string `json:"TLSHosts"`
+	// LogLevel is one of debug, info, warn or error
+	LogLevel string `json:"LogLevel"`
+	// LogRedactQueries keeps search text out of the access log
+	LogRedactQueries bool `json:"LogRedactQueries"`
+	// RateLimits maps route templates such as "/api/list/{args}" to their limit;
+	// "default" applies to every other route. Without it, requests are not rate limited
+	RateLimits map[string]rateLimit `json:"RateLimits"`
+	// TrustedProxies are addresses or CIDR ranges whose X-Forwarded-For header is believed
+	TrustedProxies []string `json:"TrustedProxies"`
+	// APIKeys are rate limited per key instead of per address when sent as X-API-Key
+	APIKeys []string `json:"APIKeys"`
+	// HeavyConcurrency is the number of expensive requests (lists, random words, names) run at once
+	HeavyConcurrency int `json:"HeavyConcurrency"`
+	// HeavyWait is how long an expensive request waits for its turn before getting a 429
+	HeavyWait duration `json:"HeavyWait"`
+	// CORSOrigins are the origins browsers may call the API from: "*", exact origins
+	// such as "https://example.com" or subdomain wildcards such as "https://*.example.com"
+	CORSOrigins []string `json:"CORSOrigins"`
+	// CORSMethods and CORSHeaders are the methods and request headers allowed in cross-origin requests
+	CORSMethods []string `json:"CORSMethods"`
+	CORSHeaders []string `json:"CORSHeaders"`
+	// CORSCredentials allows cross-origin requests with cookies or HTTP authentication
+	CORSCredentials bool `json:"CORSCredentials"`
+	// CORSMaxAge is how many seconds browsers may cache preflight responses
+	CORSMaxAge int `json:"CORSMaxAge"`
 }
 
 // Version contains the API and Fwew version information.
@@ -37,16 +104,17 @@ type Version struct {
 	DictBuild   string `json:"DictVersion"`
 }
 
-// number represents a Na'vi number.
-type number struct {
-	Name    string `json:"name"`
-	Octal   string `json:"octal"`
-	Decimal string `json:"decimal"`
+// dictState describes a loaded dictionary.
+type dictState struct {
+	DictBuild string `json:"dictBuild"`
+	Words     int    `json:"words"`
 }
 
-// message represents an error message.
-type message struct {
-	Message string `json:"message"`
+// updateResult represents the outcome of a dictionary reload.
+type updateResult struct {
+	Message string    `json:"message"`
+	Old     dictState `json:"old"`
+	New     dictState `json:"new"`
 }
 
 // load data from config.json into Config struct
@@ -60,207 +128,102 @@ func loadConfig() {
 		config.Port = "8080"
 		config.WebRoot = "https://localhost"
 	}
+	if config.BatchMaxSize <= 0 {
+		config.BatchMaxSize = 100
+	}
+	if config.BatchConcurrency <= 0 {
+		config.BatchConcurrency = 4
+	}
+	if config.NoResults == "" {
+		config.NoResults = "error"
+	}
+	if config.CacheMaxBytes == 0 {
+		config.CacheMaxBytes = 32 << 20
+	}
+	if config.CacheMaxBytes > 0 {
+		responseCache = newLRUCache(config.CacheMaxBytes)
+	}
+	if config.CacheMaxAge <= 0 {
+		config.CacheMaxAge = 300
+	}
+	defaultServerConfig()
+	if config.HeavyConcurrency <= 0 {
+		config.HeavyConcurrency = runtime.NumCPU()
+	}
+	heavySlots = make(chan struct{}, config.HeavyConcurrency)
+	if config.HeavyWait.Duration <= 0 {
+		config.HeavyWait.Duration = time.Second
+	}
+	parseTrustedProxies()
+	defaultCORSConfig()
 }
 
+// List the endpoints with their expected parameters
 func getEndpoints(w http.ResponseWriter, r *http.Request) {
-	var endpointsJSON = `{ 
-	"ROOT/": "Fwew API Index", 
-	"ROOT/fwew/{nav}": "Search Word Na'vi -> Local (returns 2-Dimensional Word array)", 
-	"ROOT/fwew-reef/{strict}/{nav}": "Search Word Reef Na'vi -> Local (returns 2-Dimensional Word array)",
-	"ROOT/fwew-strict/{nav}": "Search Word Na'vi -> Local (returns 2-Dimensional Word array)", 
-	"ROOT/fwew/r/{lang}/{local}": "Search Word Local -> Na'vi (returns 2-Dimensional Word array)", 
-	"ROOT/fwew-1d/{nav}": "search Word Na'vi -> Local (returns 1-Dimensional Word array)", 
-	"ROOT/fwew-1d/r/{lang}/{local}": "Search Word Local -> Na'vi (returns 1-Dimensional Word array)'", 
-	"ROOT/fwew-simple/{strict}/{nav}": "Search Na'vi -> Local without checking affixes (returns 2-Dimensional Word array)", 
-	"ROOT/homonyms": "List Na'vi Homonyms", 
-	"ROOT/lenition": "Na'vi Lenition Table", 
-	"ROOT/list": "List all Words (returns 1-Dimensional Word array)", 
-	"ROOT/list/{args}": "List Words with attribute filtering", 
-	"ROOT/list2/{c}/{args}": "List Words with attribute filtering and check-digraphs options", 
-	"ROOT/api/list-help/{lang}": "Show all the commands that can be put into list or random",
-	"ROOT/multi-ipa": "List Words with multiple IPA values (alternative pronunciation)", 
-	"ROOT/multiwordwords": "List Words that have two or more parts separated by a space", 
-	"ROOT/name/alu/{n}/{s}/{nm}/{am}/{dialect}": "Generate title style name(s)", 
-	"ROOT/name/full/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}": "Generate Na'vi names in full canonical format",
-	"ROOT/name/full/d/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}": "Generate Na'vi names in full canonical format.  Stop before Discord's 2000 character limit",
-	"ROOT/name/single/{n}/{s}/{dialect}": "Generate single Na'vi names", 
-	"ROOT/number/{word}": "Search a Na'vi number word to see the decimal and octal numeral forms", 
-	"ROOT/number/r/{num}": "Search an integer number between 0 and 32767 to see the Na'vi word and octal numeral forms", 
-	"ROOT/oddballs": "List Words that are canon but contradict Na'vi syllable rules", 
-	"ROOT/phonemedistros": "Get Phoneme Distribution data in English",
-	"ROOT/phonemedistros/{lang}": "Get Phoneme Distribution data", 
-	"ROOT/random/{n}": "Get random Words", 
-	"ROOT/random/{n}/{args}": "Get random Words with attribute filtering", 
-	"ROOT/random2/{n}/{c}": "Get random Words with check-digraphs options", 
-	"ROOT/random2/{n}/{c}/{args}": "Get random Words with attribute filtering and check-digraphs options", 
-	"ROOT/reef/{i}": "Get Reef Na'vi syllables and IPA by Forest Na'vi IPA", 
-	"ROOT/search/{lang}/{words}": "Search Na'vi <-> Local", 
-	"ROOT/search-reef/{lang}/{words}": "Search Na'vi <-> Local", 
-	"ROOT/total-words/": "Get the number of Words in the dictionary as a number", 
-	"ROOT/total-words/{lang}": "Get the number of Words in the dictionary as a complete sentence in the specified language", 
-	"ROOT/update": "Reload the dictionary cache", 
-	"ROOT/valid/{i}": "Check if a given word string (e.g., name, loan word, etc.) follows all Na'vi syllable rules.  Return results in English.",
-	"ROOT/valid/{lang}/{i}": "Check if a given word string (e.g., name, loan word, etc.) follows all Na'vi syllable rules.  Return results in specified language",
-	"ROOT/valid/d/{lang}/{i}": "Check if a given word string follows all Na'vi syllable rules.  Return results in specified language under Discord's 2000 character limit.",
-	"ROOT/version": "Version information" 
-}`
-	endpointsJSON = strings.ReplaceAll(endpointsJSON, "ROOT", config.WebRoot)
-	endpointsJSON = strings.ReplaceAll(endpointsJSON, "\n", "")
-	endpointsJSON = strings.ReplaceAll(endpointsJSON, "\t", "")
-	w.Write([]byte(endpointsJSON))
+	endpoints := map[string]string{}
+	for _, rt := range routes {
+		endpoint := config.WebRoot + apiPath(rt.Path)
+		var query []string
+		for _, p := range rt.Params {
+			if p.In == "query" && p.Required {
+				query = append(query, p.Name+"={"+p.Name+"}")
+			}
+		}
+		if len(query) > 0 {
+			endpoint += "?" + strings.Join(query, "&")
+		}
+		summary := rt.Summary
+		if len(rt.Methods) > 0 {
+			summary += " (" + strings.Join(rt.Methods, ", ") + ")"
+		}
+		endpoints[endpoint] = summary
+	}
+
+	render(w, r, endpoints)
 }
 
 // Search Na'vi words and return results in natural languages
 func searchWord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	navi := vars["nav"]
-
-	words, err := fwew.TranslateFromNaviHash(navi, true, false, false)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
+	writeTranslation(w, r, translateOptions{Query: vars["nav"], Affixes: true})
 }
 
 // Search Na'vi words and return results in natural languages
 func searchWordReef(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	navi := vars["nav"]
-	strictString := vars["strict"]
-	strict := false
-	if strings.ToLower(strictString) == "true" {
-		strict = true
-	}
-
-	words, err := fwew.TranslateFromNaviHash(navi, true, strict, true)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
+	strict := strings.ToLower(vars["strict"]) == "true"
+	writeTranslation(w, r, translateOptions{Query: vars["nav"], Affixes: true, Strict: strict, Reef: true})
 }
 
 // Search Na'vi words and return results in natural languages
 func searchWordStrict(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	navi := vars["nav"]
-
-	words, err := fwew.TranslateFromNaviHash(navi, true, true, false)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
+	writeTranslation(w, r, translateOptions{Query: vars["nav"], Affixes: true, Strict: true})
 }
 
 // Search natural language words and return Na'vi words
 func searchWordReverse(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	languageCode := vars["lang"]
-	localized := vars["local"]
-
-	words := fwew.TranslateToNaviHash(localized, languageCode)
-	if len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
+	writeTranslation(w, r, translateOptions{Query: vars["local"], Reverse: true, Lang: vars["lang"]})
 }
 
 // Old endpoint: return a 1d array of words instead of the normal 2d array
 func searchWord1d(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	navi := vars["nav"]
-
-	words, err := fwew.TranslateFromNaviHash(navi, true, false, false)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	oneDWords := []fwew.Word{}
-	for _, a := range words {
-		oneDWords = append(oneDWords, a...)
-	}
-
-	json.NewEncoder(w).Encode(oneDWords)
+	writeTranslation(w, r, translateOptions{Query: vars["nav"], Affixes: true, Flat: true})
 }
 
 // Old endpoint: return a 1d array of words instead of the normal 2d array
 func searchWordReverse1d(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	languageCode := vars["lang"]
-	localized := vars["local"]
-
-	words := fwew.TranslateToNaviHash(localized, languageCode)
-	if len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	oneDWords := []fwew.Word{}
-	for _, a := range words {
-		oneDWords = append(oneDWords, a...)
-	}
-
-	json.NewEncoder(w).Encode(oneDWords)
+	writeTranslation(w, r, translateOptions{Query: vars["local"], Reverse: true, Lang: vars["lang"], Flat: true})
 }
 
 // Search words without checking for productive derivations
 func simpleSearchWord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	navi := vars["nav"]
-
-	words, err := fwew.TranslateFromNaviHash(navi, false, false, false)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
-}
-
-// Search words without checking for productive derivations
-func simpleStrictSearchWord(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	navi := vars["nav"]
-
-	words, err := fwew.TranslateFromNaviHash(navi, false, true, false)
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
-		return
-	}
-
-	json.NewEncoder(w).Encode(words)
+	strict := strings.ToLower(vars["strict"]) == "true"
+	writeTranslation(w, r, translateOptions{Query: vars["nav"], Strict: strict})
 }
 
 // Input Na'vi or natural language words for searching
@@ -271,15 +234,12 @@ func searchBidirectional(w http.ResponseWriter, r *http.Request) {
 
 	words, err := fwew.BidirectionalSearch(inputWords, true, languageCode, false)
 	if err != nil || len(words) == 0 {
-
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	setResultCount(r, len(words))
+	renderSearch(w, r, words, false)
 }
 
 // Input Na'vi or natural language words for searching
@@ -290,15 +250,20 @@ func searchBidirectionalReef(w http.ResponseWriter, r *http.Request) {
 
 	words, err := fwew.BidirectionalSearch(inputWords, true, languageCode, true)
 	if err != nil || len(words) == 0 {
-
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	setResultCount(r, len(words))
+	renderSearch(w, r, words, false)
+}
+
+// "words first" and "words last" keep dictionary order, everything else is alphabetical
+func listDefaultSort(args string) string {
+	if strings.Contains(args, "words first") || strings.Contains(args, "words last") {
+		return "none"
+	}
+	return "alpha"
 }
 
 // List all words with specified parameters
@@ -308,21 +273,12 @@ func listWords(w http.ResponseWriter, r *http.Request) {
 	args := strings.Split(uncommadArgs, " ")
 
 	words, err := fwew.List(args, uint8(0))
-	if !(strings.Contains(uncommadArgs, "words first") || strings.Contains(uncommadArgs, "words last")) {
-		sort.SliceStable(words, func(i, j int) bool {
-			return fwew.AlphabetizeHelper(words[i].Navi, words[j].Navi)
-		})
-	}
-
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+	if err != nil {
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	writeWordList(w, r, words, listDefaultSort(uncommadArgs))
 }
 
 // Same as above but with extra options for digraph detection
@@ -331,177 +287,283 @@ func listWords2(w http.ResponseWriter, r *http.Request) {
 	uncommadArgs := strings.ReplaceAll(vars["args"], ", ", ",")
 	args := strings.Split(uncommadArgs, " ")
 
-	c := strings.Split(vars["c"], " ")
-	checkDigraphs := uint8(1)
-	if c[0] == "maybe" {
-		checkDigraphs = 0
-	} else if c[0] == "false" {
-		checkDigraphs = 2
-	}
+	checkDigraphs := digraphMode(vars["c"])
 
 	words, err := fwew.List(args, checkDigraphs)
-	if !(strings.Contains(uncommadArgs, "words first") || strings.Contains(uncommadArgs, "words last")) {
-		sort.SliceStable(words, func(i, j int) bool {
-			return fwew.AlphabetizeHelper(words[i].Navi, words[j].Navi)
-		})
-	}
-	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+	if err != nil {
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	writeWordList(w, r, words, listDefaultSort(uncommadArgs))
+}
+
+// the checkDigraphs argument of fwew.List and fwew.Random for the c path variable
+func digraphMode(c string) uint8 {
+	switch strings.Split(c, " ")[0] {
+	case "maybe":
+		return 0
+	case "false":
+		return 2
+	}
+	return 1
 }
 
 // Get the commands for list and random in the specified language
 func listWordsHelp(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	lc := vars["lang"]
-	a, _ := fwew.ListHelp(lc)
+	a, err := fwew.ListHelp(lc)
+	if err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
 
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
+}
+
+// lang must be one of the languages fwew-lib knows
+func checkLang(lang string) *apiError {
+	if !slices.Contains(languages, lang) {
+		return errInvalidParam("lang", lang, "one of "+strings.Join(languages, ", "), "")
+	}
+	return nil
+}
+
+// parse the named path variables as decimal integers
+func intVars(vars map[string]string, names ...string) ([]int, *apiError) {
+	ints := make([]int, len(names))
+	for i, name := range names {
+		n, err := strconv.Atoi(vars[name])
+		if err != nil {
+			return nil, errInvalidParam(name, vars[name], "a decimal integer", "invalidDecimalError")
+		}
+		ints[i] = n
+	}
+	return ints, nil
 }
 
 // Return a list of random words without specified parameters
 func getRandomWords(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	n, err := strconv.Atoi(vars["n"])
-	if err != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
+	ints, e := intVars(vars, "n")
+	if e != nil {
+		writeError(w, e)
 		return
 	}
+	n := ints[0]
 
 	args := strings.Split(vars["args"], " ")
-	words, err := fwew.Random(n, args, uint8(1))
+	seed, ok := requestSeed(w, r)
+	if !ok {
+		return
+	}
+	words, err := randomWords(seed, n, args, uint8(1))
 	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	setResultCount(r, len(words))
+	renderSeeded(w, r, seed, words)
 }
 
 // Return a list of random words with specified parameters
 func getRandomWords2(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	n, err := strconv.Atoi(vars["n"])
-	c := strings.Split(vars["c"], " ")
-	checkDigraphs := uint8(1)
-	if c[0] == "maybe" {
-		checkDigraphs = 0
-	} else if c[0] == "false" {
-		checkDigraphs = 2
-	}
-	if err != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
+	ints, e := intVars(vars, "n")
+	if e != nil {
+		writeError(w, e)
 		return
 	}
+	n := ints[0]
+	checkDigraphs := digraphMode(vars["c"])
 
 	args := strings.Split(vars["args"], " ")
-	words, err := fwew.Random(n, args, checkDigraphs)
+	seed, ok := requestSeed(w, r)
+	if !ok {
+		return
+	}
+	words, err := randomWords(seed, n, args, checkDigraphs)
 	if err != nil || len(words) == 0 {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+		writeNoResults(w, r)
 		return
 	}
 
-	json.NewEncoder(w).Encode(words)
+	setResultCount(r, len(words))
+	renderSeeded(w, r, seed, words)
 }
 
-// Turn Arabic numerals into a Na'vi number
-func searchNumber(w http.ResponseWriter, r *http.Request) {
-	var n number
-	vars := mux.Vars(r)
-	d, err := fwew.NaviToNumber(vars["word"])
-	if err != nil {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+// Return the lenition patterns in Na'vi language, with the note on ' in the lang query parameter (default en)
+func getLenitionTable(w http.ResponseWriter, r *http.Request) {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+	if e := checkLang(lang); e != nil {
+		writeError(w, e)
 		return
 	}
-	n.Name = vars["word"]
-	n.Decimal = fmt.Sprintf("%d", d)
-	n.Octal = fmt.Sprintf("%#o", d)
+	disappears, ok := lenitionDisappears[lang]
+	if !ok {
+		disappears = lenitionDisappears["en"]
+	}
 
-	json.NewEncoder(w).Encode(n)
+	var table pairs
+	for _, rule := range lenitionRules {
+		to := rule.To
+		if to == "" {
+			to = disappears
+		}
+		table = append(table, [2]string{rule.From, to})
+	}
+	render(w, r, table)
 }
 
-// Turn a Na'vi number into Arabic numerals
-func searchNumberReverse(w http.ResponseWriter, r *http.Request) {
-	var n number
-	vars := mux.Vars(r)
-	num, err := strconv.ParseInt(vars["num"], 0, 0)
-	if err != nil {
-		var m message
-		m.Message = fmt.Sprintf("%s: %s", fwew.Text("invalidIntError"), vars["n"])
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+// Version of fwew-api
+func getVersion(w http.ResponseWriter, r *http.Request) {
+	render(w, r, version)
+}
+
+// Reload the dictionary cache.
+// Requires POST and a valid admin token or signature (see auth.go)
+func update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, newError(http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed: "+r.Method))
+		return
+	}
+
+	if !adminConfigured() {
+		writeError(w, newError(http.StatusForbidden, codeForbidden, "update disabled"))
+		return
+	}
+
+	if !authorizeAdmin(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="fwew-api"`)
+		writeError(w, newError(http.StatusUnauthorized, codeUnauthorized, "unauthorized"))
+		return
+	}
+
+	dictReloading.Store(true)
+	defer dictReloading.Store(false)
+
+	// download next to the current dictionary, without holding dictLock,
+	// so that searches are served from the old dictionary meanwhile
+	file := fwew.FindDictionaryFile()
+	if file == "" {
+		log.Print("update failed: no dictionary file found")
+		writeError(w, newError(http.StatusInternalServerError, codeInternal, "Update failed"))
 		return
 	}
-	word, err := fwew.NumberToNavi(int(num))
+	downloaded := file + ".new"
+	if err := fwew.DownloadDict(downloaded); err != nil {
+		os.Remove(downloaded)
+		// the dictionary being served is unchanged
+		dictReloadFailures.Add(1)
+		log.Printf("update failed: %v", err)
+		writeError(w, newError(http.StatusInternalServerError, codeInternal, "Update failed"))
+		return
+	}
+
+	// wait for in-flight searches to finish and keep new ones out
+	// until the whole dictionary has been swapped in
+	dictLock.Lock()
+	defer dictLock.Unlock()
+
+	var result updateResult
+	result.Old = dictState{DictBuild: version.DictBuild, Words: fwew.GetDictSizeSimple()}
+
+	err := swapDictionary(file, downloaded)
+	// whatever is loaded now, its build is the one reported
+	dictionaryLoaded()
 	if err != nil {
-		var m message
-		m.Message = "no results"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(m)
+		log.Printf("update failed: %v", err)
+		writeError(w, newError(http.StatusInternalServerError, codeInternal, "Update failed"))
 		return
 	}
-	n.Name = word
-	n.Decimal = fmt.Sprintf("%d", num)
-	n.Octal = fmt.Sprintf("%#o", num)
 
-	json.NewEncoder(w).Encode(n)
+	result.Message = "Update successful"
+	result.New = dictState{DictBuild: version.DictBuild, Words: fwew.GetDictSizeSimple()}
+
+	render(w, r, result)
 }
 
-// Return the lenition patterns in Na'vi language
-func getLenitionTable(w http.ResponseWriter, r *http.Request) {
-	lenitionTableJSON := `{"kx":"k","px":"p","tx":"t","k":"h","p":"f","t":"s","ts":"s","'":"(disappears, except before ll or rr)"}`
-	w.Write([]byte(lenitionTableJSON))
+// Replace the dictionary file with a downloaded one and load it, as fwew.UpdateDict
+// does after downloading. If the new dictionary cannot be loaded, the old file is
+// put back and loaded again. The caller holds dictLock for writing.
+func swapDictionary(file, downloaded string) error {
+	backup := file + ".old"
+	if err := os.Rename(file, backup); err != nil {
+		os.Remove(downloaded)
+		return err
+	}
+	if err := os.Rename(downloaded, file); err != nil {
+		os.Rename(backup, file)
+		return err
+	}
+
+	err := loadDictionary()
+	recordDictLoad(err)
+	if err == nil {
+		os.Remove(backup)
+		return nil
+	}
+
+	if restoreErr := os.Rename(backup, file); restoreErr != nil {
+		log.Printf("restoring the old dictionary failed: %v", restoreErr)
+		return err
+	}
+	// ready again if the old dictionary loads
+	recordDictLoad(loadDictionary())
+	return err
 }
 
-// Version of fwew-api
-func getVersion(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(version)
+// (re)build fwew-lib's caches from the dictionary file
+func loadDictionary() error {
+	if err := fwew.CacheDict(); err != nil {
+		return err
+	}
+	fwew.UncacheHashDict()
+	if err := fwew.CacheDictHash(); err != nil {
+		return err
+	}
+	fwew.UncacheHashDict2()
+	if err := fwew.CacheDictHash2(); err != nil {
+		return err
+	}
+	if fwew.GetDictSizeSimple() == 0 {
+		return errors.New("dictionary is empty")
+	}
+	return nil
 }
 
-func update(w http.ResponseWriter, r *http.Request) {
-	err := fwew.UpdateDict()
-	if err != nil {
-		var m message
-		m.Message = "Update failed"
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(m)
-		return
-	} else {
-		var m message
-		m.Message = "Update successful"
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(m)
-		return
+// record that a new dictionary has been loaded
+func dictionaryLoaded() {
+	if file := fwew.FindDictionaryFile(); file != "" {
+		version.DictBuild = fwew.SHA1Hash(file)
+	}
+	dictLoaded = time.Now()
+	currentDict.Store(&dictState{DictBuild: version.DictBuild, Words: fwew.GetDictSizeSimple()})
+	buildSuggestIndex()
+	buildAutocompleteTries()
+	buildAffixEntries()
+	if responseCache != nil {
+		responseCache.Clear()
 	}
 }
 
 // Return one-word Na'vi names (or new root words) with or without specified parameters
 func getSingleNames(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	n, err1 := strconv.Atoi(vars["n"])
-	s, err2 := strconv.Atoi(vars["s"])
-	dialect := vars["dialect"]
-	d := 0
-
-	if err1 != nil || err2 != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
+	ints, e := intVars(vars, "n", "s")
+	if e != nil {
+		writeError(w, e)
 		return
 	}
+	n, s := ints[0], ints[1]
+	dialect := vars["dialect"]
+	d := 0
 
 	if dialect == "forest" {
 		d = 1
@@ -509,25 +571,21 @@ func getSingleNames(w http.ResponseWriter, r *http.Request) {
 		d = 2
 	}
 
-	names := fwew.SingleNames(n, d, s)
-	json.NewEncoder(w).Encode(names)
+	writeSeededNames(w, r, func() string { return fwew.SingleNames(n, d, s) })
 }
 
 // Return Na'vi names of the full canonical Na'vi name format (with or without specified parameters)
 func getFullNames(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ending := vars["ending"]
-	n, err1 := strconv.Atoi(vars["n"])
-	s1, err2 := strconv.Atoi(vars["s1"])
-	s2, err3 := strconv.Atoi(vars["s2"])
-	s3, err4 := strconv.Atoi(vars["s3"])
-	dialect := vars["dialect"]
-	d := 0
-
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
+	ints, e := intVars(vars, "n", "s1", "s2", "s3")
+	if e != nil {
+		writeError(w, e)
 		return
 	}
+	n, s1, s2, s3 := ints[0], ints[1], ints[2], ints[3]
+	dialect := vars["dialect"]
+	d := 0
 
 	if dialect == "forest" {
 		d = 1
@@ -535,8 +593,7 @@ func getFullNames(w http.ResponseWriter, r *http.Request) {
 		d = 2
 	}
 
-	names := fwew.FullNames(ending, n, d, [3]int{s1, s2, s3}, false)
-	json.NewEncoder(w).Encode(names)
+	writeSeededNames(w, r, func() string { return fwew.FullNames(ending, n, d, [3]int{s1, s2, s3}, false) })
 }
 
 // Same as above but stop before Discord's 2000 character limit
@@ -545,17 +602,14 @@ func getFullNames(w http.ResponseWriter, r *http.Request) {
 func getFullNamesDiscord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ending := vars["ending"]
-	n, err1 := strconv.Atoi(vars["n"])
-	s1, err2 := strconv.Atoi(vars["s1"])
-	s2, err3 := strconv.Atoi(vars["s2"])
-	s3, err4 := strconv.Atoi(vars["s3"])
-	dialect := vars["dialect"]
-	d := 0
-
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
+	ints, e := intVars(vars, "n", "s1", "s2", "s3")
+	if e != nil {
+		writeError(w, e)
 		return
 	}
+	n, s1, s2, s3 := ints[0], ints[1], ints[2], ints[3]
+	dialect := vars["dialect"]
+	d := 0
 
 	if dialect == "forest" {
 		d = 1
@@ -563,25 +617,23 @@ func getFullNamesDiscord(w http.ResponseWriter, r *http.Request) {
 		d = 2
 	}
 
-	names := fwew.FullNames(ending, n, d, [3]int{s1, s2, s3}, true)
-	json.NewEncoder(w).Encode(names)
+	writeSeededNames(w, r, func() string { return fwew.FullNames(ending, n, d, [3]int{s1, s2, s3}, true) })
 }
 
 // Return names of the format "[name] alu [noun] [adjective]"" with or without specified parameters
 func getNameAlu(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	n, err1 := strconv.Atoi(vars["n"])
-	s, err2 := strconv.Atoi(vars["s"])
+	ints, e := intVars(vars, "n", "s")
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	n, s := ints[0], ints[1]
 	noun_mode := vars["nm"]
 	adj_mode := vars["am"]
 	dialect := vars["dialect"]
 	d := 0
 
-	if err1 != nil || err2 != nil {
-		json.NewEncoder(w).Encode(fwew.Text("invalidDecimalError"))
-		return
-	}
-
 	if dialect == "forest" {
 		d = 1
 	} else if dialect == "reef" {
@@ -614,14 +666,13 @@ func getNameAlu(w http.ResponseWriter, r *http.Request) {
 		am = 7
 	}
 
-	names := fwew.NameAlu(n, d, s, nm, am)
-	json.NewEncoder(w).Encode(names)
+	writeSeededNames(w, r, func() string { return fwew.NameAlu(n, d, s, nm, am) })
 }
 
 // Return the phoneme distributions in English
 func getPhonemeDistrosEN(w http.ResponseWriter, r *http.Request) {
 	a := fwew.GetPhonemeDistrosMap("en")
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
 }
 
 // Return the phoneme distributions in the specified language
@@ -629,60 +680,79 @@ func getPhonemeDistros(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	languageCode := vars["lang"]
 	a := fwew.GetPhonemeDistrosMap(languageCode)
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
 }
 
 // Get all words with spaces
 func getMultiwordWords(w http.ResponseWriter, r *http.Request) {
 	a := fwew.GetMultiwordWords()
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
 }
 
 // Get all words with multiple dictionary entries for one spelling
 func getHomonyms(w http.ResponseWriter, r *http.Request) {
-	a, _ := fwew.GetHomonyms()
-	json.NewEncoder(w).Encode(a)
+	a, err := fwew.GetHomonyms()
+	if err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
+	setResultCount(r, len(a))
+	render(w, r, a)
 }
 
 // Get all words which seemingly violate Na'vi phonotactic rules
 func getOddballs(w http.ResponseWriter, r *http.Request) {
-	a, _ := fwew.GetOddballs()
-	json.NewEncoder(w).Encode(a)
+	a, err := fwew.GetOddballs()
+	if err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
+	setResultCount(r, len(a))
+	render(w, r, a)
 }
 
 // Get all words with more than one pronunciation listed
 func getMultiIPA(w http.ResponseWriter, r *http.Request) {
-	a, _ := fwew.GetMultiIPA()
-	json.NewEncoder(w).Encode(a)
+	a, err := fwew.GetMultiIPA()
+	if err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
+	setResultCount(r, len(a))
+	render(w, r, a)
 }
 
 // Get the number of words in the dictionary
 func getDictLenSimple(w http.ResponseWriter, r *http.Request) {
 	a := fwew.GetDictSizeSimple()
 
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
 }
 
 // Get the number of words in the dictionary as a complete sentence in the specified language
 func getDictLen(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	lc := vars["lang"]
-	a, _ := fwew.GetDictSize(lc)
+	a, err := fwew.GetDictSize(lc)
+	if err != nil {
+		writeError(w, errInternal(err))
+		return
+	}
 
-	json.NewEncoder(w).Encode(a)
+	render(w, r, a)
 }
 
 // Turn an interdialect IPA into a reef IPA and Romanization
 func getReefFromIpa(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	json.NewEncoder(w).Encode(fwew.ReefMe(vars["i"], false))
+	render(w, r, fwew.ReefMe(vars["i"], false))
 }
 
 // Say whether or not a word follows Na'vi syllable rules.
 // Return results in English
 func getValidityEN(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	json.NewEncoder(w).Encode(fwew.IsValidNavi(vars["i"], "en", false))
+	render(w, r, fwew.IsValidNavi(vars["i"], "en", false))
 }
 
 // Say whether or not a word follows Na'vi syllable rules.
@@ -690,7 +760,7 @@ func getValidityEN(w http.ResponseWriter, r *http.Request) {
 func getValidityDiscord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	lc := vars["lang"]
-	json.NewEncoder(w).Encode(fwew.IsValidNavi(vars["i"], lc, true))
+	render(w, r, fwew.IsValidNavi(vars["i"], lc, true))
 }
 
 // Say whether or not a word follows Na'vi syllable rules.
@@ -698,67 +768,76 @@ func getValidityDiscord(w http.ResponseWriter, r *http.Request) {
 func getValidity(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	lc := vars["lang"]
-	json.NewEncoder(w).Encode(fwew.IsValidNavi(vars["i"], lc, false))
+	render(w, r, fwew.IsValidNavi(vars["i"], lc, false))
 }
 
-// set the Header Content-Type to "application/json" for all endpoints
-func contentTypeMiddleware(next http.Handler) http.Handler {
+// routes that do not read the dictionary through the lock:
+// the reload itself, and the operational endpoints that must answer during a reload
+var unlockedRoutes = map[string]bool{"update": true, "healthz": true, "readyz": true, "metrics": true}
+
+// hold the dictionary read lock for the duration of every request except
+// the reload itself, so no request observes a partially loaded dictionary
+func dictionaryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if unlockedRoutes[routeName(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		dictLock.RLock()
+		defer dictLock.RUnlock()
 		next.ServeHTTP(w, r)
 	})
 }
 
 func handleRequests() {
+	serve(newRouter())
+}
+
+// the router of every endpoint, with its middleware
+func newRouter() *mux.Router {
 	myRouter := mux.NewRouter().StrictSlash(true)
-	myRouter.Use(contentTypeMiddleware)
-
-	myRouter.HandleFunc("/api/", getEndpoints)
-	myRouter.HandleFunc("/api/fwew/{nav}", searchWord)
-	myRouter.HandleFunc("/api/fwew-reef/{strict}/{nav}", searchWordReef)
-	myRouter.HandleFunc("/api/fwew-strict/{nav}", searchWordStrict)
-	myRouter.HandleFunc("/api/fwew/r/{lang}/{local}", searchWordReverse)
-	myRouter.HandleFunc("/api/fwew-1d/{nav}", searchWord1d)
-	myRouter.HandleFunc("/api/fwew-1d/r/{lang}/{local}", searchWordReverse1d)
-	myRouter.HandleFunc("/api/fwew-simple/{strict}/{nav}", simpleSearchWord)
-	myRouter.HandleFunc("/api/homonyms", getHomonyms)
-	myRouter.HandleFunc("/api/lenition", getLenitionTable)
-	myRouter.HandleFunc("/api/list", listWords)
-	myRouter.HandleFunc("/api/list/{args}", listWords)
-	myRouter.HandleFunc("/api/list2/{c}/{args}", listWords2)
-	myRouter.HandleFunc("/api/list-help/{lang}", listWordsHelp)
-	myRouter.HandleFunc("/api/multi-ipa", getMultiIPA)
-	myRouter.HandleFunc("/api/multiwordwords", getMultiwordWords)
-	myRouter.HandleFunc("/api/name/alu/{n}/{s}/{nm}/{am}/{dialect}", getNameAlu)
-	myRouter.HandleFunc("/api/name/full/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}", getFullNames)
-	myRouter.HandleFunc("/api/name/full/d/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}", getFullNamesDiscord)
-	myRouter.HandleFunc("/api/name/single/{n}/{s}/{dialect}", getSingleNames)
-	myRouter.HandleFunc("/api/number/{word}", searchNumber)
-	myRouter.HandleFunc("/api/number/r/{num}", searchNumberReverse)
-	myRouter.HandleFunc("/api/oddballs", getOddballs)
-	myRouter.HandleFunc("/api/phonemedistros", getPhonemeDistrosEN)
-	myRouter.HandleFunc("/api/phonemedistros/{lang}", getPhonemeDistros)
-	myRouter.HandleFunc("/api/random/{n}", getRandomWords)
-	myRouter.HandleFunc("/api/random/{n}/{args}", getRandomWords)
-	myRouter.HandleFunc("/api/random2/{n}/{c}", getRandomWords2)
-	myRouter.HandleFunc("/api/random2/{n}/{c}/{args}", getRandomWords2)
-	myRouter.HandleFunc("/api/reef/{i}", getReefFromIpa)
-	myRouter.HandleFunc("/api/search/{lang}/{words}", searchBidirectional)
-	myRouter.HandleFunc("/api/search-reef/{lang}/{words}", searchBidirectionalReef)
-	myRouter.HandleFunc("/api/total-words", getDictLenSimple)
-	myRouter.HandleFunc("/api/total-words/{lang}", getDictLen)
-	myRouter.HandleFunc("/api/update", update)
-	myRouter.HandleFunc("/api/valid/{i}", getValidityEN)
-	myRouter.HandleFunc("/api/valid/{lang}/{i}", getValidity)
-	myRouter.HandleFunc("/api/valid/d/{lang}/{i}", getValidityDiscord)
-	myRouter.HandleFunc("/api/version", getVersion)
-
-	log.Fatal(http.ListenAndServe(":"+config.Port, myRouter))
+	myRouter.NotFoundHandler = accessLogMiddleware(unmatchedMetricsMiddleware(corsMiddleware(http.HandlerFunc(notFound))))
+	myRouter.MethodNotAllowedHandler = accessLogMiddleware(unmatchedMetricsMiddleware(corsMiddleware(http.HandlerFunc(methodNotAllowed))))
+	myRouter.Use(accessLogMiddleware)
+	myRouter.Use(metricsMiddleware)
+	myRouter.Use(corsMiddleware)
+	myRouter.Use(rateLimitMiddleware)
+	myRouter.Use(dictionaryMiddleware)
+
+	// operational endpoints, outside of /api and the route table
+	myRouter.HandleFunc("/healthz", getHealth).Name("healthz")
+	myRouter.HandleFunc("/readyz", getReady).Name("readyz")
+	myRouter.HandleFunc("/metrics", getMetrics).Name("metrics")
+
+	for _, rt := range routes {
+		handler := rt.Handler
+		if rt.Heavy {
+			handler = heavy(handler)
+		}
+		if rt.Cache {
+			handler = cacheable(handler)
+		}
+		r := myRouter.HandleFunc(rt.Path, handler)
+		if len(rt.Methods) > 0 {
+			// OPTIONS is answered by corsMiddleware
+			r.Methods(append(slices.Clone(rt.Methods), http.MethodOptions)...)
+		}
+		if rt.Name != "" {
+			r.Name(rt.Name)
+		}
+	}
+	return myRouter
 }
 
 func main() {
 	loadConfig()
+	setupLogging()
 	log.Print(fwew.StartEverything())
+	if fwew.GetDictSizeSimple() == 0 {
+		recordDictLoad(errors.New("dictionary is empty"))
+	} else {
+		recordDictLoad(nil)
+	}
+	dictionaryLoaded()
 	handleRequests()
 }
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// Load the small dictionary in testdata/.fwew, so that tests never download the real one.
+// fwew-lib looks for .fwew in the working directory first.
+func TestMain(m *testing.M) {
+	if err := os.Chdir("testdata"); err != nil {
+		log.Fatal(err)
+	}
+	fwew.StartEverything()
+	dictionaryLoaded()
+	os.Exit(m.Run())
+}
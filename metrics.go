@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// upper bounds in seconds of the request duration histogram buckets
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// routeMetrics counts the requests of one route and method.
+type routeMetrics struct {
+	statuses map[int]uint64
+	buckets  []uint64 // requests per latency bucket, not cumulative; last is +Inf
+	sum      float64
+	count    uint64
+}
+
+// requestMetrics collects per-route request counts and latencies.
+type requestMetrics struct {
+	mu     sync.Mutex
+	routes map[[2]string]*routeMetrics // keyed by route template and method
+}
+
+var metrics = requestMetrics{routes: map[[2]string]*routeMetrics{}}
+
+// dictionary reload outcomes, including the initial load
+var (
+	dictReloads        atomic.Uint64
+	dictReloadFailures atomic.Uint64
+	dictLoadError      atomic.Value // string, empty after a successful load
+)
+
+// the dictionary being served, readable without waiting for a reload to finish
+var (
+	dictReloading atomic.Bool               // set while /update swaps the dictionary
+	currentDict   atomic.Pointer[dictState] // nil until the first load
+)
+
+// record one finished request
+func (m *requestMetrics) observe(route string, method string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := [2]string{route, method}
+	rm, ok := m.routes[key]
+	if !ok {
+		rm = &routeMetrics{statuses: map[int]uint64{}, buckets: make([]uint64, len(latencyBuckets)+1)}
+		m.routes[key] = rm
+	}
+
+	seconds := elapsed.Seconds()
+	rm.statuses[status]++
+	rm.buckets[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	rm.sum += seconds
+	rm.count++
+}
+
+// statusWriter remembers the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+// the path template of the route a request matched
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unmatched"
+}
+
+// the name of the route a request matched, if any
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName()
+	}
+	return ""
+}
+
+// count requests per route, method and status, and time them
+func metricsMiddleware(next http.Handler) http.Handler {
+	return observeRequests(next, routeTemplate)
+}
+
+// count and time the requests no route matches, the 404 and 405 responses,
+// under the route "unmatched", so that scanned paths do not each get a series
+func unmatchedMetricsMiddleware(next http.Handler) http.Handler {
+	return observeRequests(next, func(*http.Request) string { return "unmatched" })
+}
+
+// count requests per route, as given by route, method and status, and time them
+func observeRequests(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		metrics.observe(route(r), r.Method, sw.status, time.Since(start))
+	})
+}
+
+// record the outcome of loading the dictionary
+func recordDictLoad(err error) {
+	if err != nil {
+		dictReloadFailures.Add(1)
+		dictLoadError.Store(err.Error())
+		return
+	}
+	dictReloads.Add(1)
+	dictLoadError.Store("")
+}
+
+// Liveness: the process is up and serving
+func getHealth(w http.ResponseWriter, r *http.Request) {
+	render(w, r, map[string]string{"status": "ok"})
+}
+
+// readiness represents the state reported by /readyz.
+type readiness struct {
+	Ready     bool   `json:"ready"`
+	Reloading bool   `json:"reloading"`
+	Words     int    `json:"words"`
+	DictBuild string `json:"dictBuild"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Readiness: a dictionary with at least one word is loaded and the last load succeeded.
+// A running reload is reported but does not make the server unready, since searches
+// only wait for it to finish.
+func getReady(w http.ResponseWriter, r *http.Request) {
+	var state readiness
+	state.Error, _ = dictLoadError.Load().(string)
+	state.Reloading = dictReloading.Load()
+	if dict := currentDict.Load(); dict != nil {
+		state.Words = dict.Words
+		state.DictBuild = dict.DictBuild
+	}
+	state.Ready = state.Error == "" && state.Words > 0
+
+	status := http.StatusOK
+	if !state.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	renderStatus(w, r, status, state)
+}
+
+// Metrics in the Prometheus text exposition format
+func getMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b strings.Builder
+
+	metrics.mu.Lock()
+	keys := make([][2]string, 0, len(metrics.routes))
+	for key := range metrics.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+
+	b.WriteString("# HELP fwew_http_requests_total Requests by route, method and status.\n")
+	b.WriteString("# TYPE fwew_http_requests_total counter\n")
+	for _, key := range keys {
+		rm := metrics.routes[key]
+		statuses := make([]int, 0, len(rm.statuses))
+		for status := range rm.statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "fwew_http_requests_total{route=%q,method=%q,status=\"%d\"} %d\n", key[0], key[1], status, rm.statuses[status])
+		}
+	}
+
+	b.WriteString("# HELP fwew_http_request_duration_seconds Request duration by route and method.\n")
+	b.WriteString("# TYPE fwew_http_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		rm := metrics.routes[key]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += rm.buckets[i]
+			fmt.Fprintf(&b, "fwew_http_request_duration_seconds_bucket{route=%q,method=%q,le=%q} %d\n", key[0], key[1], strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "fwew_http_request_duration_seconds_bucket{route=%q,method=%q,le=\"+Inf\"} %d\n", key[0], key[1], rm.count)
+		fmt.Fprintf(&b, "fwew_http_request_duration_seconds_sum{route=%q,method=%q} %g\n", key[0], key[1], rm.sum)
+		fmt.Fprintf(&b, "fwew_http_request_duration_seconds_count{route=%q,method=%q} %d\n", key[0], key[1], rm.count)
+	}
+	metrics.mu.Unlock()
+
+	var hits, misses uint64
+	if responseCache != nil {
+		hits, misses = responseCache.Stats()
+	}
+	ratio := 0.0
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+	b.WriteString("# HELP fwew_cache_hits_total Responses served from the response cache.\n")
+	b.WriteString("# TYPE fwew_cache_hits_total counter\n")
+	fmt.Fprintf(&b, "fwew_cache_hits_total %d\n", hits)
+	b.WriteString("# HELP fwew_cache_misses_total Cacheable responses that had to be computed.\n")
+	b.WriteString("# TYPE fwew_cache_misses_total counter\n")
+	fmt.Fprintf(&b, "fwew_cache_misses_total %d\n", misses)
+	b.WriteString("# HELP fwew_cache_hit_ratio Share of cacheable requests served from the cache.\n")
+	b.WriteString("# TYPE fwew_cache_hit_ratio gauge\n")
+	fmt.Fprintf(&b, "fwew_cache_hit_ratio %g\n", ratio)
+
+	b.WriteString("# HELP fwew_dictionary_reloads_total Dictionary loads by result, including the initial load.\n")
+	b.WriteString("# TYPE fwew_dictionary_reloads_total counter\n")
+	fmt.Fprintf(&b, "fwew_dictionary_reloads_total{result=\"success\"} %d\n", dictReloads.Load())
+	fmt.Fprintf(&b, "fwew_dictionary_reloads_total{result=\"failure\"} %d\n", dictReloadFailures.Load())
+	reloading := 0
+	if dictReloading.Load() {
+		reloading = 1
+	}
+	b.WriteString("# HELP fwew_dictionary_reloading Whether a dictionary reload is running.\n")
+	b.WriteString("# TYPE fwew_dictionary_reloading gauge\n")
+	fmt.Fprintf(&b, "fwew_dictionary_reloading %d\n", reloading)
+	if dictLock.TryRLock() {
+		b.WriteString("# HELP fwew_dictionary_loaded_timestamp_seconds Time the current dictionary was loaded.\n")
+		b.WriteString("# TYPE fwew_dictionary_loaded_timestamp_seconds gauge\n")
+		fmt.Fprintf(&b, "fwew_dictionary_loaded_timestamp_seconds %d\n", dictLoaded.Unix())
+		b.WriteString("# HELP fwew_dictionary_words Number of words in the dictionary.\n")
+		b.WriteString("# TYPE fwew_dictionary_words gauge\n")
+		fmt.Fprintf(&b, "fwew_dictionary_words %d\n", fwew.GetDictSizeSimple())
+		dictLock.RUnlock()
+	}
+
+	w.Write([]byte(b.String()))
+}
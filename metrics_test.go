@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// requests no route matches are counted, under one route
+func TestUnmatchedMetrics(t *testing.T) {
+	router := newRouter()
+	count := func(route, method string, status int) uint64 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		if rm, ok := metrics.routes[[2]string{route, method}]; ok {
+			return rm.statuses[status]
+		}
+		return 0
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/api/no/such/path", http.StatusNotFound},
+		{http.MethodGet, "/wp-login.php", http.StatusNotFound},
+		{http.MethodGet, "/api/batch/fwew", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		before := count("unmatched", tt.method, tt.status)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+		if after := count("unmatched", tt.method, tt.status); after != before+1 {
+			t.Errorf("%s %s: %d unmatched requests counted, want 1", tt.method, tt.path, after-before)
+		}
+		if count(tt.path, tt.method, tt.status) != 0 {
+			t.Errorf("%s %s counted under its own path", tt.method, tt.path)
+		}
+	}
+}
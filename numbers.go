@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+)
+
+// the largest number with a Na'vi word, 0o77777
+const maxNaviNumber = 32767
+
+// the most numbers /api/numbers returns at once, a chart of three octal digits
+const numbersMaxRange = 512
+
+// Na'vi number words by octal digit
+var (
+	numberWords   = []string{"kew", "'aw", "mune", "pxey", "tsìng", "mrr", "pukap", "kinä"} // standing alone
+	numberFinals  = []string{"", "aw", "mun", "pey", "sìng", "mrr", "fu", "hin"}            // as the last digit
+	numberFactors = []string{"", "", "me", "pxe", "tsì", "mrr", "pu", "ki"}                 // before a power of 8
+	numberPowers  = []string{"", "vo", "za", "vozam", "zazam"}                              // 8, 64, 512 and 4096
+)
+
+// the ordinal stems of the number words that change before -ve; the others take it as they are
+var ordinalStems = map[string]string{"mune": "mu", "tsìng": "tsì", "pukap": "pu", "kinä": "ki", "mun": "mu", "sìng": "sì", "hin": "hi"}
+
+// the Reef spelling of number words: ejectives become voiced stops,
+// and the unstressed ä of kinä becomes e
+var reefNumbers = strings.NewReplacer("px", "b", "tx", "d", "kx", "g", "ä", "e")
+
+// spellings of number words typed without ì or ä
+var asciiNumbers = strings.NewReplacer("tsi", "tsì", "sing", "sìng", "kina", "kinä")
+
+// number represents a Na'vi number.
+type number struct {
+	Name        string       `json:"name"`
+	Octal       string       `json:"octal"`
+	Decimal     string       `json:"decimal"`
+	Ordinal     string       `json:"ordinal"`
+	Reef        string       `json:"reef"`
+	ReefOrdinal string       `json:"reefOrdinal"`
+	Parts       []numberPart `json:"parts"`
+}
+
+// numberPart is the word for one octal digit of a number.
+type numberPart struct {
+	Navi    string `json:"navi"`
+	Reef    string `json:"reef"`
+	Digit   int    `json:"digit"`
+	Place   int    `json:"place"` // 1, 8, 64, 512 or 4096
+	Value   int    `json:"value"`
+	Meaning string `json:"meaning"` // e.g. "2 × 8"
+}
+
+// the text of a part, e.g. "mevol = 2 × 8 (16)"
+func (p numberPart) String() string {
+	if p.Place == 1 {
+		return fmt.Sprintf("%s = %d", p.Navi, p.Value)
+	}
+	return fmt.Sprintf("%s = %s (%d)", p.Navi, p.Meaning, p.Value)
+}
+
+// Turn Arabic numerals into a Na'vi number
+func searchNumber(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	d, err := naviToNumber(vars["word"])
+	if err != nil {
+		writeError(w, errInvalidParam("word", vars["word"], "a Na'vi number word", "invalidNumericError"))
+		return
+	}
+	n := newNumber(d)
+	n.Name = vars["word"]
+
+	render(w, r, n)
+}
+
+// Turn a Na'vi number into Arabic numerals
+func searchNumberReverse(w http.ResponseWriter, r *http.Request) {
+	num, e := numberParam("num", mux.Vars(r)["num"])
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	render(w, r, newNumber(num))
+}
+
+// List the numbers from one integer to another, both included, for number charts
+func getNumbers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var bounds [2]int
+	for i, name := range []string{"from", "to"} {
+		value := query.Get(name)
+		if value == "" {
+			writeError(w, errMissingParam(name))
+			return
+		}
+		n, e := numberParam(name, value)
+		if e != nil {
+			writeError(w, e)
+			return
+		}
+		bounds[i] = n
+	}
+	from, to := bounds[0], bounds[1]
+	if to < from {
+		writeError(w, errInvalidCombination("to", "to must not be less than from"))
+		return
+	}
+	if to-from >= numbersMaxRange {
+		writeError(w, errInvalidCombination("to", fmt.Sprintf("at most %d numbers at once", numbersMaxRange)))
+		return
+	}
+
+	numbers := make([]number, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, newNumber(n))
+	}
+	setResultCount(r, len(numbers))
+	render(w, r, numbers)
+}
+
+// fwew.NaviToNumber, also accepting number words typed without ì or ä, such as tsivol.
+// fwew-lib reads such words only in part (volsing as vol), so they are always respelled.
+func naviToNumber(word string) (int, error) {
+	return fwew.NaviToNumber(asciiNumbers.Replace(strings.ToLower(word)))
+}
+
+// Parse an integer between 0 and 32767: decimal, octal as 0o17 or 017, or octal as "17 octal"
+func numberParam(name string, value string) (int, *apiError) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	// an explicit base, as base 0 would also take 0x1f, 0b101 and 1_000
+	base := 10
+	if digits, ok := strings.CutSuffix(s, "octal"); ok {
+		s, base = strings.TrimSpace(digits), 8
+	} else if digits, ok := strings.CutPrefix(s, "0o"); ok {
+		s, base = digits, 8
+	} else if len(s) > 1 && s[0] == '0' {
+		base = 8
+	}
+	n, err := strconv.ParseInt(s, base, 0)
+	if err != nil || n < 0 || n > maxNaviNumber {
+		return 0, errInvalidParam(name, value, "an integer between 0 and 32767, decimal or octal (0o17 or \"17 octal\")", "invalidIntError")
+	}
+	return int(n), nil
+}
+
+// The Na'vi number n, between 0 and 32767, in every form
+func newNumber(n int) number {
+	parts := numberParts(n)
+	var name strings.Builder
+	for _, p := range parts {
+		name.WriteString(p.Navi)
+	}
+
+	last := parts[len(parts)-1].Navi
+	stem := strings.TrimSuffix(name.String(), last) + firstOf(ordinalStems[last], last)
+
+	return number{
+		Name:        name.String(),
+		Octal:       fmt.Sprintf("%#o", n),
+		Decimal:     strconv.Itoa(n),
+		Ordinal:     stem + "ve",
+		Reef:        reefNumbers.Replace(name.String()),
+		ReefOrdinal: reefNumbers.Replace(stem + "ve"),
+		Parts:       parts,
+	}
+}
+
+// The words for the octal digits of n, highest first, which make up its Na'vi number word.
+// This follows fwew.NumberToNavi: vo takes l before a last digit of 0 or 1,
+// za takes m except before vo and before a last digit of 2 or more, and mm is written m.
+func numberParts(n int) []numberPart {
+	if n < len(numberWords) {
+		return []numberPart{{Navi: numberWords[n], Reef: reefNumbers.Replace(numberWords[n]), Digit: n, Place: 1, Value: n, Meaning: strconv.Itoa(n)}}
+	}
+
+	var digits [5]int
+	for i := range digits {
+		digits[i] = n >> (3 * i) % 8
+	}
+
+	var parts []numberPart
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if d == 0 {
+			continue
+		}
+		place := 1 << (3 * i)
+		p := numberPart{Digit: d, Place: place, Value: d * place, Meaning: fmt.Sprintf("%d × %d", d, place)}
+		switch {
+		case i == 0:
+			p.Navi, p.Meaning = numberFinals[d], strconv.Itoa(d)
+		case i == 1 && digits[0] <= 1:
+			p.Navi = numberFactors[d] + numberPowers[i] + "l"
+		case i == 2 && (digits[1] > 1 || digits[1] == 0 && digits[0] <= 1):
+			p.Navi = numberFactors[d] + numberPowers[i] + "m"
+		default:
+			p.Navi = numberFactors[d] + numberPowers[i]
+		}
+		if k := len(parts) - 1; k >= 0 && strings.HasSuffix(parts[k].Navi, "m") && strings.HasPrefix(p.Navi, "m") {
+			parts[k].Navi = strings.TrimSuffix(parts[k].Navi, "m")
+		}
+		parts = append(parts, p)
+	}
+	for i := range parts {
+		parts[i].Reef = reefNumbers.Replace(parts[i].Navi)
+	}
+	return parts
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// every number is spelled as fwew-lib spells it, and its parts add up to it
+func TestNumberPartsMatchFwew(t *testing.T) {
+	for n := 0; n <= maxNaviNumber; n++ {
+		want, err := fwew.NumberToNavi(n)
+		if err != nil {
+			t.Fatalf("fwew.NumberToNavi(%d): %v", n, err)
+		}
+
+		var name strings.Builder
+		sum := 0
+		for _, p := range numberParts(n) {
+			name.WriteString(p.Navi)
+			sum += p.Value
+			if p.Value != p.Digit*p.Place {
+				t.Errorf("numberParts(%d): %s is worth %d, not %d × %d", n, p.Navi, p.Value, p.Digit, p.Place)
+			}
+		}
+		if name.String() != strings.ToLower(want) {
+			t.Errorf("numberParts(%d) spell %q, fwew-lib has %q", n, name.String(), want)
+		}
+		if sum != n {
+			t.Errorf("numberParts(%d) add up to %d", n, sum)
+		}
+		if back, err := naviToNumber(name.String()); err != nil || back != n {
+			t.Errorf("naviToNumber(%q) = %d, %v, want %d", name.String(), back, err, n)
+		}
+	}
+}
+
+func TestNewNumber(t *testing.T) {
+	tests := []struct {
+		n                                       int
+		name, octal, ordinal, reef, reefOrdinal string
+	}{
+		{0, "kew", "0", "kewve", "kew", "kewve"},
+		{1, "'aw", "01", "'awve", "'aw", "'awve"},
+		{2, "mune", "02", "muve", "mune", "muve"},
+		{3, "pxey", "03", "pxeyve", "bey", "beyve"},
+		{7, "kinä", "07", "kive", "kine", "kive"},
+		{8, "vol", "010", "volve", "vol", "volve"},
+		{10, "vomun", "012", "vomuve", "vomun", "vomuve"},
+		{16, "mevol", "020", "mevolve", "mevol", "mevolve"},
+		{27, "pxevopey", "033", "pxevopeyve", "bevopey", "bevopeyve"},
+		{100, "zamtsìvosìng", "0144", "zamtsìvosìve", "zamtsìvosìng", "zamtsìvosìve"},
+		{4096, "zazam", "010000", "zazamve", "zazam", "zazamve"},
+	}
+	for _, tt := range tests {
+		got := newNumber(tt.n)
+		if got.Name != tt.name || got.Octal != tt.octal || got.Ordinal != tt.ordinal || got.Reef != tt.reef || got.ReefOrdinal != tt.reefOrdinal {
+			t.Errorf("newNumber(%d) = %s %s %s %s %s, want %s %s %s %s %s", tt.n,
+				got.Name, got.Octal, got.Ordinal, got.Reef, got.ReefOrdinal,
+				tt.name, tt.octal, tt.ordinal, tt.reef, tt.reefOrdinal)
+		}
+	}
+}
+
+func TestNumberParts(t *testing.T) {
+	tests := map[int]string{
+		9:     "vol = 1 × 8 (8); aw = 1",
+		65:    "zam = 1 × 64 (64); aw = 1",
+		100:   "zam = 1 × 64 (64); tsìvo = 4 × 8 (32); sìng = 4",
+		32767: "kizazam = 7 × 4096 (28672); kivozam = 7 × 512 (3584); kizam = 7 × 64 (448); kivo = 7 × 8 (56); hin = 7",
+	}
+	for n, want := range tests {
+		var parts []string
+		for _, p := range numberParts(n) {
+			parts = append(parts, p.String())
+		}
+		if got := strings.Join(parts, "; "); got != want {
+			t.Errorf("numberParts(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestNaviToNumberASCII(t *testing.T) {
+	tests := map[string]int{
+		"tsìvol":  32,
+		"tsivol":  32,
+		"kinä":    7,
+		"kina":    7,
+		"volsing": 12,
+		"Mevol":   16,
+	}
+	for word, want := range tests {
+		if n, err := naviToNumber(word); err != nil || n != want {
+			t.Errorf("naviToNumber(%q) = %d, %v, want %d", word, n, err, want)
+		}
+	}
+}
+
+func TestNumberParam(t *testing.T) {
+	tests := []struct {
+		value string
+		n     int
+		ok    bool
+	}{
+		{"17", 17, true},
+		{"0o17", 15, true},
+		{"017", 15, true},
+		{"17 octal", 15, true},
+		{" 17 OCTAL ", 15, true},
+		{"0", 0, true},
+		{"32767", 32767, true},
+		{"32768", 0, false},
+		{"-1", 0, false},
+		{"18 octal", 0, false},
+		{"vol", 0, false},
+		{"0x1f", 0, false},
+		{"0b101", 0, false},
+		{"1_000", 0, false},
+		{"0o", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		n, e := numberParam("num", tt.value)
+		if ok := e == nil; ok != tt.ok || n != tt.n {
+			t.Errorf("numberParam(%q) = %d, error %v, want %d, ok %v", tt.value, n, e, tt.n, tt.ok)
+		}
+	}
+}
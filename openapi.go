@@ -0,0 +1,233 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// matches the {variables} of a mux path template
+var pathVarPattern = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Serve the OpenAPI 3 description of this API
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	render(w, r, buildOpenAPI(routes))
+}
+
+// build an OpenAPI 3 document from the route table
+func buildOpenAPI(routes []route) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	// every error response uses the same body
+	errorSchema := schemaOf(reflect.TypeOf(apiError{}), schemas)
+
+	for _, rt := range routes {
+		path := apiPath(rt.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+
+		// every rendered response can also be had as YAML, CSV, TSV or text, see render
+		schema := schemaOf(reflect.TypeOf(rt.Response), schemas)
+		text := map[string]any{"schema": map[string]any{"type": "string"}}
+		content := map[string]any{
+			"application/json":          map[string]any{"schema": schema},
+			"application/yaml":          map[string]any{"schema": schema},
+			"text/csv":                  text,
+			"text/tab-separated-values": text,
+			"text/plain":                text,
+		}
+		if rt.Produces != "" {
+			content = map[string]any{rt.Produces: map[string]any{"schema": schema}}
+		}
+		op := map[string]any{
+			"summary": rt.Summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     content,
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": errorSchema},
+					},
+				},
+			},
+		}
+		if rt.Name != "" {
+			op["operationId"] = rt.Name
+		}
+		if params := openAPIParams(rt); len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(rt.Body), schemas)},
+				},
+			}
+		}
+
+		for _, method := range rt.methods() {
+			paths[path][strings.ToLower(method)] = op
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Fwew API",
+			"description": "Na'vi dictionary search",
+			"version":     version.APIVersion,
+		},
+		"servers":    []any{map[string]any{"url": config.WebRoot}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// path of a route relative to the web root, e.g. /api/fwew/{nav} -> /fwew/{nav}
+func apiPath(path string) string {
+	path = strings.TrimPrefix(path, "/api")
+	return pathVarPattern.ReplaceAllString(path, "{$1}")
+}
+
+// the OpenAPI parameter objects of a route
+func openAPIParams(rt route) []any {
+	declared := map[string]param{}
+	for _, p := range rt.Params {
+		declared[p.Name] = p
+	}
+
+	var params []any
+	for _, match := range pathVarPattern.FindAllStringSubmatch(rt.Path, -1) {
+		p, ok := declared[match[1]]
+		if !ok {
+			p = param{Name: match[1], In: "path", Type: "string"}
+		}
+		p.Required = true
+		params = append(params, paramObject(p))
+	}
+	for _, p := range rt.Params {
+		if p.In == "query" {
+			params = append(params, paramObject(p))
+		}
+	}
+	for _, p := range outputParams {
+		if _, ok := declared[p.Name]; !ok && rt.Produces == "" {
+			params = append(params, paramObject(p))
+		}
+	}
+	return params
+}
+
+func paramObject(p param) map[string]any {
+	schema := map[string]any{"type": p.Type}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	obj := map[string]any{
+		"name":     p.Name,
+		"in":       p.In,
+		"required": p.Required,
+		"schema":   schema,
+	}
+	if p.Description != "" {
+		obj["description"] = p.Description
+	}
+	return obj
+}
+
+// The JSON schema of a Go type. Named structs are added to schemas
+// and referenced, everything else is inlined.
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		// anonymous struct types have no name to refer to
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name := t.Name()
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := schemas[name]; ok {
+			return ref
+		}
+		// reserve the name first so recursive types terminate
+		schemas[name] = nil
+		schemas[name] = structSchema(t, schemas)
+		return ref
+	}
+
+	return map[string]any{}
+}
+
+// The object schema of a struct type. The fields of embedded structs are
+// flattened into it, as encoding/json does.
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	addProperties(t, properties, schemas)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// Add the JSON properties of the fields of struct type t to properties.
+// Fields of t hide those of the structs it embeds, as in encoding/json.
+func addProperties(t reflect.Type, properties map[string]any, schemas map[string]any) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Name
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == "-" {
+			continue
+		}
+		if tagName != "" {
+			name = tagName
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && tagName == "" && fieldType.Kind() == reflect.Struct {
+			embedded = append(embedded, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+	}
+
+	for _, e := range embedded {
+		promoted := map[string]any{}
+		addProperties(e, promoted, schemas)
+		for name, schema := range promoted {
+			if _, ok := properties[name]; !ok {
+				properties[name] = schema
+			}
+		}
+	}
+}
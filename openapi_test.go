@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// the document as clients see it
+func openAPIDocument(t *testing.T) map[string]any {
+	t.Helper()
+	b, err := json.Marshal(buildOpenAPI(routes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// every $ref in v, with the path to it
+func refs(v any, path string, found map[string]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				found[path] = ref
+			}
+			refs(value, path+"/"+key, found)
+		}
+	case []any:
+		for i, value := range v {
+			refs(value, fmt.Sprintf("%s/%d", path, i), found)
+		}
+	}
+}
+
+func TestOpenAPIValid(t *testing.T) {
+	doc := openAPIDocument(t)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	componentName := regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+	for name, schema := range schemas {
+		if !componentName.MatchString(name) {
+			t.Errorf("component name %q", name)
+		}
+		if schema == nil {
+			t.Errorf("component %q has no schema", name)
+		}
+	}
+
+	found := map[string]string{}
+	refs(doc, "", found)
+	for path, ref := range found {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if _, exists := schemas[name]; !ok || !exists {
+			t.Errorf("%s: %s does not resolve", path, ref)
+		}
+	}
+
+	operationIDs := map[string]string{}
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			op := op.(map[string]any)
+			if id, ok := op["operationId"].(string); ok {
+				if other, seen := operationIDs[id]; seen && other != path {
+					t.Errorf("operationId %s used by %s and %s", id, other, path)
+				}
+				operationIDs[id] = path
+			}
+
+			// every path variable is a required path parameter, and there are no others
+			var declared []string
+			params, _ := op["parameters"].([]any)
+			for _, p := range params {
+				p := p.(map[string]any)
+				if p["in"] == "path" {
+					declared = append(declared, p["name"].(string))
+					if p["required"] != true {
+						t.Errorf("%s %s: path parameter %s not required", method, path, p["name"])
+					}
+				}
+			}
+			var variables []string
+			for _, match := range pathVarPattern.FindAllStringSubmatch(path, -1) {
+				variables = append(variables, match[1])
+			}
+			if !slices.Equal(declared, variables) {
+				t.Errorf("%s %s: path parameters %q, want %q", method, path, declared, variables)
+			}
+		}
+	}
+}
+
+// embedded structs are flattened and anonymous ones inlined, as encoding/json has them
+func TestSchemaOfStructs(t *testing.T) {
+	type inner struct {
+		A string `json:"a"`
+		B int    `json:"b"`
+	}
+	type outer struct {
+		inner
+		B     bool `json:"b"` // hides inner's b
+		C     struct{ D string }
+		Skip  string `json:"-"`
+		small string
+	}
+
+	schemas := map[string]any{}
+	got := schemaOf(reflect.TypeOf(outer{}), schemas)
+	want := map[string]any{"type": "object", "properties": map[string]any{
+		"a": map[string]any{"type": "string"},
+		"b": map[string]any{"type": "boolean"},
+		"C": map[string]any{"type": "object", "properties": map[string]any{"D": map[string]any{"type": "string"}}},
+	}}
+	if got["$ref"] != "#/components/schemas/outer" || !reflect.DeepEqual(schemas["outer"], want) {
+		t.Errorf("schemaOf(outer) = %v, components %v, want %v", got, schemas, want)
+	}
+	if _, ok := schemas[""]; ok {
+		t.Error("anonymous struct added as a component without a name")
+	}
+}
+
+// Check that v, decoded JSON, has the shape of schema. Properties may be left out,
+// but there may be no others, and null stands for a nil pointer, slice or map.
+func matchSchema(v any, schema map[string]any, schemas map[string]any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return matchSchema(v, schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any), schemas, path)
+	}
+	if v == nil || len(schema) == 0 {
+		return nil
+	}
+
+	mismatch := func(want string) []string {
+		return []string{fmt.Sprintf("%s: %T, want %s", path, v, want)}
+	}
+	var errs []string
+	switch schema["type"] {
+	case "object":
+		object, ok := v.(map[string]any)
+		if !ok {
+			return mismatch("object")
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		for key, value := range object {
+			switch {
+			case properties[key] != nil:
+				errs = append(errs, matchSchema(value, properties[key].(map[string]any), schemas, path+"."+key)...)
+			case additional != nil:
+				errs = append(errs, matchSchema(value, additional, schemas, path+"."+key)...)
+			default:
+				errs = append(errs, fmt.Sprintf("%s: property %q not in the schema", path, key))
+			}
+		}
+	case "array":
+		array, ok := v.([]any)
+		if !ok {
+			return mismatch("array")
+		}
+		for i, item := range array {
+			errs = append(errs, matchSchema(item, schema["items"].(map[string]any), schemas, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return mismatch("string")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return mismatch("integer")
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return mismatch("number")
+		}
+	}
+	return errs
+}
+
+// the response of every route has the schema the document gives it,
+// with the dictionary in testdata
+func TestOpenAPIResponses(t *testing.T) {
+	doc := openAPIDocument(t)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	paths := doc["paths"].(map[string]any)
+	router := newRouter()
+	saved, savedSlots := config, heavySlots
+	defer func() { config, heavySlots = saved, savedSlots }()
+	config.RateLimits = nil
+	config.HeavyWait.Duration = time.Second
+	heavySlots = make(chan struct{}, 1)
+
+	requests := []string{
+		"/api/fwew/tuteti",
+		"/api/fwew/tuteti?rank=true&explain=true",
+		"/api/fwew-1d/tute?explain=true",
+		"/api/fwew-simple/true/tute",
+		"/api/fwew/r/en/person",
+		"/api/search/en/tute%20person?rank=true",
+		"/api/v2/translate?q=oel&explain=true",
+		"/api/list",
+		"/api/random/2",
+		"/api/conjugate/taron?tense=past",
+		"/api/conjugate/taron/paradigm",
+		"/api/decline/tute",
+		"/api/lenite/tute",
+		"/api/lenite/r/sute",
+		"/api/lenition",
+		"/api/number/pxey",
+		"/api/number/r/3",
+		"/api/calc?expr=mune%2Bpxey",
+		"/api/suggest/tutte",
+		"/api/autocomplete/en/tu",
+		"/api/total-words",
+		"/api/version",
+		"/api/homonyms",
+		"/api/name/single/1/2/forest",
+	}
+	for _, uri := range requests {
+		r := httptest.NewRequest(http.MethodGet, uri, nil)
+		var match mux.RouteMatch
+		if !router.Match(r, &match) || match.Route == nil {
+			t.Errorf("%s: no route", uri)
+			continue
+		}
+		template, _ := match.Route.GetPathTemplate()
+		op := paths[apiPath(template)].(map[string]any)["get"].(map[string]any)
+		content := op["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+		schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", uri, w.Code, w.Body)
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Errorf("%s: %v", uri, err)
+			continue
+		}
+		for _, err := range matchSchema(v, schema, schemas, "response") {
+			t.Errorf("%s: %s", uri, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// points of the ranking of search results
+const (
+	exactMatchPoints  = 100 // the search is the headword itself
+	foldedMatchPoints = 50  // the search is the headword without its diacritics or apostrophes
+	affixPoints       = -10 // for every affix of a derived form
+	canonPoints       = 10  // the source names Frommer or an official publication
+	nonstandardPoints = -20 // fwew-lib had to correct the spelling
+)
+
+// words in a Source naming Paul Frommer, his blog or an official publication;
+// sources such as community word lists name none of these. "Avatar" is left out,
+// since community sources name the films too.
+var canonSourceWords = []string{"frommer", "naviteri", "asg", "pandorapedia"}
+
+// points for parts of speech, by how common they are in running text;
+// anything not listed gets none
+var partOfSpeechPoints = map[string]int{
+	"n.": 20, "v.": 20, "vtr.": 20, "vin.": 20, "vtrm.": 20, "vim.": 20, "svin.": 15,
+	"adj.": 15, "adv.": 15, "pn.": 15,
+	"adp.": 10, "adp+": 10, "conj.": 10, "part.": 10, "inter.": 10, "num.": 10, "sbd.": 10,
+	"intj.": 5, "prop.n.": 5, "ph.": 5,
+}
+
+// rank is how likely a search result is the intended reading of the search.
+type rank struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// the text of a rank, e.g. "score 120: exact headword match (+100); ..."
+func (k rank) String() string {
+	return fmt.Sprintf("score %d: %s", k.Score, strings.Join(k.Reasons, "; "))
+}
+
+// Score word as a reading of query:
+// exact headword matches over matches without diacritics over derived forms, fewer affixes over more,
+// common over rare parts of speech, and words from a canon source.
+func rankWord(word fwew.Word, query string) rank {
+	var k rank
+	add := func(points int, reason string) {
+		k.Score += points
+		k.Reasons = append(k.Reasons, fmt.Sprintf("%s (%+d)", reason, points))
+	}
+
+	switch {
+	case headwordKey(word.Navi) == headwordKey(query):
+		add(exactMatchPoints, "exact headword match")
+	case autocompleteKey(word.Navi) == autocompleteKey(query):
+		add(foldedMatchPoints, "headword match without diacritics")
+	}
+
+	affixes := len(word.Affixes.Prefix) + len(word.Affixes.Infix) + len(word.Affixes.Suffix) + len(word.Affixes.Lenition)
+	if affixes == 1 {
+		add(affixPoints, "derived form with 1 affix")
+	} else if affixes > 1 {
+		add(affixes*affixPoints, fmt.Sprintf("derived form with %d affixes", affixes))
+	}
+
+	best, bestPOS := 0, ""
+	for _, pos := range strings.Split(word.PartOfSpeech, ",") {
+		pos = strings.TrimSpace(pos)
+		if points := partOfSpeechPoints[pos]; points > best {
+			best, bestPOS = points, pos
+		}
+	}
+	if best > 0 {
+		add(best, "part of speech "+bestPOS)
+	}
+
+	if isCanonSource(word.Source) {
+		add(canonPoints, "canon source")
+	}
+
+	for _, comment := range word.Affixes.Comment {
+		if strings.HasPrefix(comment, "correct form of") {
+			add(nonstandardPoints, "non-standard spelling")
+			break
+		}
+	}
+
+	return k
+}
+
+// s lowercased, with typographic apostrophes made plain
+func headwordKey(s string) string {
+	return strings.ToLower(strings.NewReplacer("’", "'", "‘", "'").Replace(strings.TrimSpace(s)))
+}
+
+// whether a Source names Frommer or an official publication
+func isCanonSource(source string) bool {
+	source = strings.ToLower(source)
+	if strings.Contains(source, "survival guide") {
+		return true
+	}
+	words := strings.FieldsFunc(source, func(c rune) bool { return !unicode.IsLetter(c) && !unicode.IsDigit(c) })
+	for _, w := range words {
+		if slices.Contains(canonSourceWords, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank the results of every searched word and sort them best first.
+// The first word of a group, the search itself, stays first.
+func rankWords(groups [][]annotatedWord) {
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		start := 0
+		if group[0].ID == "" {
+			start = 1
+		}
+		query := group[0].Navi
+		for i := start; i < len(group); i++ {
+			k := rankWord(group[i].Word, query)
+			group[i].Rank = &k
+		}
+		slices.SortStableFunc(group[start:], func(a, b annotatedWord) int {
+			return b.Rank.Score - a.Rank.Score
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// a word with the given number of suffixes
+func rankedWord(id, navi, pos, source string, suffixes int) annotatedWord {
+	word := fwew.Word{ID: id, Navi: navi, PartOfSpeech: pos, Source: source}
+	for range suffixes {
+		word.Affixes.Suffix = append(word.Affixes.Suffix, "ti")
+	}
+	return annotatedWord{Word: word}
+}
+
+func TestRankWordsOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		group []annotatedWord
+		ids   []string // best first
+	}{
+		{"exact over folded", "kaltxì", []annotatedWord{
+			rankedWord("1", "kaltxi", "intj.", "ASG", 0),
+			rankedWord("2", "Kaltxì", "intj.", "ASG", 0),
+		}, []string{"2", "1"}},
+		{"folded over derived", "kaltxi", []annotatedWord{
+			rankedWord("1", "kal", "n.", "ASG", 1),
+			rankedWord("2", "kaltxì", "intj.", "ASG", 0),
+		}, []string{"2", "1"}},
+		{"typographic apostrophe is exact", "’eylan", []annotatedWord{
+			rankedWord("1", "eylan", "n.", "ASG", 0),
+			rankedWord("2", "'eylan", "n.", "ASG", 0),
+		}, []string{"2", "1"}},
+		{"fewer affixes first", "tuteti", []annotatedWord{
+			rankedWord("1", "tut", "n.", "ASG", 2),
+			rankedWord("2", "tute", "n.", "ASG", 1),
+		}, []string{"2", "1"}},
+		{"common part of speech first", "pxey", []annotatedWord{
+			rankedWord("1", "pxey", "num.", "ASG", 0),
+			rankedWord("2", "pxey", "n.", "ASG", 0),
+		}, []string{"2", "1"}},
+		{"canon source first", "taron", []annotatedWord{
+			rankedWord("1", "taron", "vtr.", "Avatar fan wiki", 0),
+			rankedWord("2", "taron", "vtr.", "Frommer", 0),
+		}, []string{"2", "1"}},
+	}
+	for _, tt := range tests {
+		// the search itself, which stays first
+		group := append([]annotatedWord{{Word: fwew.Word{Navi: tt.query}}}, tt.group...)
+		rankWords([][]annotatedWord{group})
+		var ids []string
+		for _, w := range group {
+			ids = append(ids, w.ID)
+		}
+		if want := append([]string{""}, tt.ids...); !slices.Equal(ids, want) {
+			t.Errorf("%s: ranked %q, want %q", tt.name, ids, want)
+		}
+	}
+}
+
+func TestRankWordTiers(t *testing.T) {
+	tests := []struct {
+		navi, query string
+		score       int
+	}{
+		{"kaltxì", "kaltxì", exactMatchPoints},
+		{"Kaltxì", "kaltxì", exactMatchPoints},
+		{"kaltxì", "kaltxi", foldedMatchPoints},
+		{"'eylan", "eylan", foldedMatchPoints},
+		{"kaltxì", "kal", 0},
+	}
+	for _, tt := range tests {
+		if k := rankWord(fwew.Word{Navi: tt.navi}, tt.query); k.Score != tt.score {
+			t.Errorf("rankWord(%q, %q) = %v, want score %d", tt.navi, tt.query, k, tt.score)
+		}
+	}
+}
+
+func TestIsCanonSource(t *testing.T) {
+	tests := map[string]bool{
+		"Frommer":                       true,
+		"https://naviteri.org/2011/08/": true,
+		"ASG":                           true,
+		"Activist Survival Guide":       true,
+		"Pandorapedia":                  true,
+		"Avatar (2009)":                 false,
+		"PND":                           false,
+		"LearnNavi community list":      false,
+		"":                              false,
+	}
+	for source, want := range tests {
+		if got := isCanonSource(source); got != want {
+			t.Errorf("isCanonSource(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimit is the token bucket of one route: Rate requests per second on average,
+// with bursts of up to Burst requests. A Rate of 0 or less means unlimited.
+type rateLimit struct {
+	Rate  float64 `json:"Rate"`
+	Burst int     `json:"Burst"`
+}
+
+// bucket is the token bucket of one client on one route.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter holds the buckets of all clients.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+var limiter = rateLimiter{buckets: map[string]*bucket{}}
+
+// routes that are never rate limited, so that monitoring keeps working under load
+var rateLimitExempt = map[string]bool{"healthz": true, "readyz": true, "metrics": true}
+
+// the most lenient limit of /api/update, whatever RateLimits says, so that its
+// admin token or signature cannot be guessed at speed
+var updateLimit = rateLimit{Rate: 1.0 / 60, Burst: 5}
+
+// global limit on the number of heavy handlers running at once, sized in loadConfig
+var heavySlots chan struct{}
+
+// trusted proxy networks, parsed from config.TrustedProxies
+var trustedProxies []*net.IPNet
+
+// parse config.TrustedProxies; single addresses are accepted as well as CIDR ranges
+func parseTrustedProxies() {
+	trustedProxies = nil
+	for _, p := range config.TrustedProxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			continue
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// The address of the client that sent r. X-Forwarded-For is only believed when the
+// request came through a trusted proxy; the client is then the rightmost untrusted address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isTrustedProxy(ip) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		host = hop.String()
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// The rate limiting key of the client that sent r: a configured API key, or the client address
+func clientKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" && slices.Contains(config.APIKeys, key) {
+		return "key:" + key
+	}
+	return "ip:" + clientIP(r)
+}
+
+// the rate limit of a route template
+func limitFor(route string) rateLimit {
+	l, ok := config.RateLimits[route]
+	if !ok {
+		l = config.RateLimits["default"]
+	}
+	if route == "/api/update" {
+		if l.Rate <= 0 {
+			return updateLimit
+		}
+		l.Rate = min(l.Rate, updateLimit.Rate)
+		l.Burst = min(l.Burst, updateLimit.Burst)
+	}
+	return l
+}
+
+// Take one token from the bucket of key. Returns whether the request may proceed,
+// the tokens left, and how long until the next token (when refused) or a full bucket.
+func (rl *rateLimiter) take(key string, limit rateLimit, now time.Time) (bool, int, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// forget clients that have been idle long enough to have a full bucket again
+	if now.Sub(rl.lastSweep) > time.Minute {
+		for k, b := range rl.buckets {
+			if now.Sub(b.last) > 10*time.Minute {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	burst := float64(max(limit.Burst, 1))
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	full := time.Duration((burst - b.tokens) / limit.Rate * float64(time.Second))
+	return true, int(b.tokens), full
+}
+
+// seconds, rounded up, as a header value
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// Limit the request rate of each client per route, according to config.RateLimits.
+// Sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and
+// answers 429 with Retry-After when the client's bucket is empty.
+func rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		limit := limitFor(route)
+		if limit.Rate <= 0 || rateLimitExempt[routeName(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, remaining, wait := limiter.take(route+"|"+clientKey(r), limit, time.Now())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(max(limit.Burst, 1)))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(wait))
+		if !ok {
+			w.Header().Set("Retry-After", ceilSeconds(wait))
+			writeError(w, errRateLimited())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wrap an expensive handler so that at most HeavyConcurrency of them run at once.
+// Requests wait up to HeavyWait for a free slot, then get a 429.
+func heavy(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), config.HeavyWait.Duration)
+		defer cancel()
+
+		select {
+		case heavySlots <- struct{}{}:
+			defer func() { <-heavySlots }()
+			next(w, r)
+		case <-ctx.Done():
+			w.Header().Set("Retry-After", "1")
+			writeError(w, errRateLimited())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTake(t *testing.T) {
+	rl := rateLimiter{buckets: map[string]*bucket{}}
+	limit := rateLimit{Rate: 2, Burst: 3}
+	start := time.Unix(1000, 0)
+
+	// a new client has a full bucket
+	for want := 2; want >= 0; want-- {
+		ok, remaining, _ := rl.take("a", limit, start)
+		if !ok || remaining != want {
+			t.Fatalf("take = %v, %d left, want true, %d left", ok, remaining, want)
+		}
+	}
+	ok, _, wait := rl.take("a", limit, start)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("take on an empty bucket = %v, wait %v, want false, wait 500ms", ok, wait)
+	}
+
+	// other clients have buckets of their own
+	if ok, _, _ := rl.take("b", limit, start); !ok {
+		t.Errorf("take of another client refused")
+	}
+
+	// two tokens a second: one is back after half a second, no more than Burst after a long pause
+	if ok, remaining, _ := rl.take("a", limit, start.Add(500*time.Millisecond)); !ok || remaining != 0 {
+		t.Errorf("take after 500ms = %v, %d left, want true, 0 left", ok, remaining)
+	}
+	if ok, remaining, full := rl.take("a", limit, start.Add(time.Hour)); !ok || remaining != 2 || full != 500*time.Millisecond {
+		t.Errorf("take after an hour = %v, %d left, full in %v, want true, 2 left, full in 500ms", ok, remaining, full)
+	}
+}
+
+func TestLimitFor(t *testing.T) {
+	saved := config.RateLimits
+	defer func() { config.RateLimits = saved }()
+
+	tests := []struct {
+		limits map[string]rateLimit
+		route  string
+		want   rateLimit
+	}{
+		{nil, "/api/list/{args}", rateLimit{}},
+		{map[string]rateLimit{"default": {Rate: 10, Burst: 20}}, "/api/list/{args}", rateLimit{Rate: 10, Burst: 20}},
+		{map[string]rateLimit{"default": {Rate: 10, Burst: 20}, "/api/list/{args}": {Rate: 1, Burst: 2}}, "/api/list/{args}", rateLimit{Rate: 1, Burst: 2}},
+		// /api/update is limited even without RateLimits, and never more leniently than updateLimit
+		{nil, "/api/update", updateLimit},
+		{map[string]rateLimit{"default": {Rate: 10, Burst: 20}}, "/api/update", updateLimit},
+		{map[string]rateLimit{"/api/update": {Rate: 0.001, Burst: 1}}, "/api/update", rateLimit{Rate: 0.001, Burst: 1}},
+	}
+	for _, tt := range tests {
+		config.RateLimits = tt.limits
+		if got := limitFor(tt.route); got != tt.want {
+			t.Errorf("limitFor(%q) with %v = %+v, want %+v", tt.route, tt.limits, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	saved := config.TrustedProxies
+	defer func() {
+		config.TrustedProxies = saved
+		parseTrustedProxies()
+	}()
+	config.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1"}
+	parseTrustedProxies()
+
+	tests := []struct {
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"203.0.113.5:1234", nil, "203.0.113.5"},
+		// X-Forwarded-For is ignored unless the request came from a trusted proxy
+		{"203.0.113.5:1234", []string{"198.51.100.7"}, "203.0.113.5"},
+		{"192.0.2.1:1234", []string{"198.51.100.7"}, "198.51.100.7"},
+		// the client is the rightmost address no trusted proxy added
+		{"10.1.2.3:1234", []string{"6.6.6.6, 198.51.100.7, 10.0.0.9"}, "198.51.100.7"},
+		{"10.1.2.3:1234", []string{"6.6.6.6", "198.51.100.7"}, "198.51.100.7"},
+		// garbage stops the walk at the last good hop
+		{"10.1.2.3:1234", []string{"198.51.100.7, nonsense"}, "10.1.2.3"},
+		{"10.1.2.3:1234", nil, "10.1.2.3"},
+		{"[2001:db8::1]:1234", []string{"198.51.100.7"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("clientIP(%s, %q) = %s, want %s", tt.remote, tt.xff, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	saved := config.RateLimits
+	defer func() {
+		config.RateLimits = saved
+		limiter = rateLimiter{buckets: map[string]*bucket{}}
+	}()
+	config.RateLimits = map[string]rateLimit{"default": {Rate: 0.001, Burst: 1}}
+	limiter = rateLimiter{buckets: map[string]*bucket{}}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router := mux.NewRouter()
+	router.Use(rateLimitMiddleware)
+	router.HandleFunc("/healthz", ok).Name("healthz")
+	router.HandleFunc("/api/update", ok).Name("update")
+	router.HandleFunc("/api/list", ok).Name("list")
+
+	status := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w.Code
+	}
+	for _, path := range []string{"/api/list", "/api/update"} {
+		if code := status(path); code != http.StatusOK {
+			t.Errorf("first request to %s: %d", path, code)
+		}
+		if code := status(path); code != http.StatusTooManyRequests {
+			t.Errorf("second request to %s: %d, want 429", path, code)
+		}
+	}
+	for range 3 {
+		if code := status("/healthz"); code != http.StatusOK {
+			t.Errorf("/healthz: %d, want it never limited", code)
+		}
+	}
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// output formats, in order of preference when the client accepts several equally
+var outputFormats = []string{"json", "csv", "tsv", "yaml", "text"}
+
+// Content-Type of each output format
+var formatContentTypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+	"tsv":  "text/tab-separated-values; charset=utf-8",
+	"yaml": "application/yaml; charset=utf-8",
+	"text": "text/plain; charset=utf-8",
+}
+
+// output format of each media type understood in Accept
+var mediaTypeFormats = map[string]string{
+	"application/json":          "json",
+	"application/*":             "json",
+	"*/*":                       "json",
+	"text/csv":                  "csv",
+	"text/tab-separated-values": "tsv",
+	"application/yaml":          "yaml",
+	"application/x-yaml":        "yaml",
+	"text/yaml":                 "yaml",
+	"text/plain":                "text",
+	"text/*":                    "text",
+}
+
+// The output format asked for by r: ?format= if given, else the best match in Accept.
+// Requests that accept nothing we can produce get JSON.
+func negotiateFormat(r *http.Request) (string, *apiError) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		format = strings.ToLower(format)
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", errInvalidParam("format", format, strings.Join(outputFormats, ", "), "")
+		}
+		return format, nil
+	}
+
+	best, bestQ := "json", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		format, ok := mediaTypeFormats[strings.ToLower(strings.TrimSpace(mediaType))]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, nil
+}
+
+// Write v in the output format asked for by the request, see negotiateFormat.
+// CSV, TSV and text output can be limited to the columns listed in ?columns=.
+func render(w http.ResponseWriter, r *http.Request, v any) {
+	renderStatus(w, r, http.StatusOK, v)
+}
+
+// Same as render, with a status other than 200
+func renderStatus(w http.ResponseWriter, r *http.Request, status int, v any) {
+	format, e := negotiateFormat(r)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	var columns []string
+	if list := r.URL.Query().Get("columns"); list != "" && format != "json" && format != "yaml" {
+		for _, c := range strings.Split(list, ",") {
+			columns = append(columns, strings.TrimSpace(c))
+		}
+	}
+
+	if p, ok := v.(wrapper); ok && format != "json" && format != "yaml" {
+		v = p.payload()
+	}
+
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "csv":
+		err = writeDelimited(&b, v, columns, ',')
+	case "tsv":
+		err = writeDelimited(&b, v, columns, '\t')
+	case "yaml":
+		err = writeYAML(&b, v)
+	case "text":
+		err = writeText(&b, v, columns, textLang(r))
+	default:
+		err = json.NewEncoder(&b).Encode(v)
+	}
+	if err != nil {
+		var ae *apiError
+		if errors.As(err, &ae) {
+			writeError(w, ae)
+		} else {
+			writeError(w, errInternal(err))
+		}
+		return
+	}
+
+	addVary(w.Header(), "Accept")
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// add name to the Vary header unless it is already there
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// wrapper is implemented by responses that wrap their data with details, such as a seed,
+// which CSV, TSV and text output leave out.
+type wrapper interface {
+	payload() any
+}
+
+// pairs is a list of key-value pairs encoded as a JSON object, keeping its order.
+type pairs [][2]string
+
+func (p pairs) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, pair := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(pair[0])
+		value, _ := json.Marshal(pair[1])
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Write v as YAML with the same keys, in the same order, as its JSON encoding
+func writeYAML(b *bytes.Buffer, v any) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// JSON is YAML, so decoding it keeps the key order; only the flow style has to go
+	var node yaml.Node
+	if err := yaml.Unmarshal(j, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// clear the JSON flow and quoting styles of a YAML node tree
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// Write v as CSV or TSV with a header row
+func writeDelimited(b *bytes.Buffer, v any, columns []string, comma rune) error {
+	header, rows, err := tableOf(v, columns)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(b)
+	cw.Comma = comma
+	if len(header) > 0 {
+		cw.Write(header)
+	}
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+// the language of definitions in text output and of affix explanations:
+// ?lang= or the lang path variable, default English
+func textLang(r *http.Request) string {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = mux.Vars(r)["lang"]
+	}
+	if !slices.Contains(languages, lang) {
+		lang = "en"
+	}
+	return lang
+}
+
+// textRenderer is implemented by values with their own plain-text layout.
+type textRenderer interface {
+	text() string
+}
+
+// Write v as human-readable text: words as a numbered list with their definitions,
+// text as is, anything else as an aligned table
+func writeText(b *bytes.Buffer, v any, columns []string, lang string) error {
+	if len(columns) == 0 {
+		switch v := v.(type) {
+		case textRenderer:
+			b.WriteString(v.text())
+			return nil
+		case string:
+			b.WriteString(v)
+			if !strings.HasSuffix(v, "\n") {
+				b.WriteByte('\n')
+			}
+			return nil
+		case []fwew.Word:
+			writeWordsText(b, unannotated(v), lang)
+			return nil
+		case [][]fwew.Word:
+			for i, group := range v {
+				if i > 0 {
+					b.WriteByte('\n')
+				}
+				writeWordsText(b, unannotated(group), lang)
+			}
+			return nil
+		case []annotatedWord:
+			writeWordsText(b, v, lang)
+			return nil
+		case [][]annotatedWord:
+			for i, group := range v {
+				if i > 0 {
+					b.WriteByte('\n')
+				}
+				writeWordsText(b, group, lang)
+			}
+			return nil
+		}
+	}
+
+	header, rows, err := tableOf(v, columns)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	if len(header) > 0 && !(len(header) == 1 && header[0] == "value") {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Write words like the fwew command line does:
+//
+//	[1] taron [ˈt·a.ɾ·ɔn] vtr. hunt
+//
+// Search results start with the searched word, which has no ID; it is written as a heading.
+func writeWordsText(b *bytes.Buffer, words []annotatedWord, lang string) {
+	n := 0
+	for _, word := range words {
+		if word.ID == "" {
+			fmt.Fprintf(b, "%s:\n", word.Navi)
+			continue
+		}
+		n++
+		fmt.Fprintf(b, "[%d] %s [%s] %s %s", n, word.Navi, word.IPA, word.PartOfSpeech, localDefinition(word.Word, lang))
+		if affixes := affixText(word.Affixes); affixes != "" {
+			fmt.Fprintf(b, " (%s)", affixes)
+		}
+		b.WriteByte('\n')
+		if word.Rank != nil {
+			fmt.Fprintf(b, "    %s\n", word.Rank)
+		}
+		for _, e := range word.Explanations {
+			fmt.Fprintf(b, "    %s\n", e)
+		}
+	}
+}
+
+// words without annotations, for writeWordsText
+func unannotated(words []fwew.Word) []annotatedWord {
+	annotated := make([]annotatedWord, len(words))
+	for i, word := range words {
+		annotated[i] = annotatedWord{Word: word}
+	}
+	return annotated
+}
+
+// the non-empty affix lists of a word, such as "Prefix: me; Suffix: l"
+func affixText(affixes any) string {
+	v := reflect.ValueOf(affixes)
+	var parts []string
+	for i := 0; i < v.NumField(); i++ {
+		if list, ok := v.Field(i).Interface().([]string); ok && len(list) > 0 {
+			parts = append(parts, v.Type().Field(i).Name+": "+strings.Join(list, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// The table of search results, one row per word, with the searched word
+// in a leading Query column
+func searchTable(groups reflect.Value, columns []string) ([]string, [][]string, error) {
+	var rows []map[string]string
+	for i := 0; i < groups.Len(); i++ {
+		group := groups.Index(i)
+		for j := 0; j < group.Len(); j++ {
+			row := fieldsOf(group.Index(j))
+			if j == 0 && row["ID"] == "" {
+				continue
+			}
+			row["Query"] = fieldsOf(group.Index(0))["Navi"]
+			rows = append(rows, row)
+		}
+	}
+	return selectColumns(append([]string{"Query"}, structColumns(groups.Type().Elem().Elem())...), rows, columns)
+}
+
+// Turn v into a header and rows.
+// Slices give one row per element, structs and maps one column per field or key, and
+// a string one row per line. Search results ([][]fwew.Word) get a leading Query column.
+// columns selects and orders the columns, ignoring case.
+func tableOf(v any, columns []string) ([]string, [][]string, error) {
+	switch groups := v.(type) {
+	case [][]fwew.Word:
+		return searchTable(reflect.ValueOf(groups), columns)
+	case [][]annotatedWord:
+		return searchTable(reflect.ValueOf(groups), columns)
+	}
+
+	if s, ok := v.(string); ok {
+		var rows []map[string]string
+		for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+			rows = append(rows, map[string]string{"value": line})
+		}
+		return selectColumns([]string{"value"}, rows, columns)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if p, ok := v.(pairs); ok {
+			return pairsTable(p, columns)
+		}
+		var all []string
+		rows := make([]map[string]string, rv.Len())
+		for i := range rows {
+			elem := reflect.Indirect(rv.Index(i))
+			switch elem.Kind() {
+			case reflect.Struct, reflect.Map:
+				rows[i] = fieldsOf(elem)
+				if i == 0 && elem.Kind() == reflect.Struct {
+					all = structColumns(elem.Type())
+				}
+			default:
+				rows[i] = map[string]string{"value": cell(elem)}
+			}
+		}
+		if all == nil {
+			all = keysOf(rows)
+		}
+		return selectColumns(all, rows, columns)
+	case reflect.Struct:
+		return selectColumns(structColumns(rv.Type()), []map[string]string{fieldsOf(rv)}, columns)
+	case reflect.Map:
+		var p pairs
+		for _, key := range rv.MapKeys() {
+			p = append(p, [2]string{cell(key), cell(rv.MapIndex(key))})
+		}
+		sort.Slice(p, func(i, j int) bool { return p[i][0] < p[j][0] })
+		return pairsTable(p, columns)
+	}
+
+	return selectColumns([]string{"value"}, []map[string]string{{"value": cell(rv)}}, columns)
+}
+
+// a two-column table of key-value pairs
+func pairsTable(p pairs, columns []string) ([]string, [][]string, error) {
+	rows := make([]map[string]string, len(p))
+	for i, pair := range p {
+		rows[i] = map[string]string{"key": pair[0], "value": pair[1]}
+	}
+	return selectColumns([]string{"key", "value"}, rows, columns)
+}
+
+// keep the requested columns of rows, in the requested order
+func selectColumns(all []string, rows []map[string]string, columns []string) ([]string, [][]string, error) {
+	header := all
+	if len(columns) > 0 {
+		header = make([]string, len(columns))
+		for i, c := range columns {
+			j := slices.IndexFunc(all, func(name string) bool { return strings.EqualFold(name, c) })
+			if j < 0 {
+				return nil, nil, errInvalidParam("columns", c, "comma-separated column names: "+strings.Join(all, ", "), "")
+			}
+			header[i] = all[j]
+		}
+	}
+
+	table := make([][]string, len(rows))
+	for i, row := range rows {
+		table[i] = make([]string, len(header))
+		for j, name := range header {
+			table[i][j] = row[name]
+		}
+	}
+	return header, table, nil
+}
+
+// the column names of a struct type: JSON names where the fields have them
+func structColumns(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type.Kind() == reflect.Struct {
+			names = append(names, structColumns(f.Type)...)
+		} else if name := columnName(f); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func columnName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name
+}
+
+// the cells of one struct or map, by column name
+func fieldsOf(v reflect.Value) map[string]string {
+	row := map[string]string{}
+	if v.Kind() == reflect.Map {
+		for _, key := range v.MapKeys() {
+			row[cell(key)] = cell(v.MapIndex(key))
+		}
+		return row
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.Anonymous && f.Type.Kind() == reflect.Struct {
+			maps.Copy(row, fieldsOf(v.Field(i)))
+		} else if name := columnName(f); name != "" {
+			row[name] = cell(v.Field(i))
+		}
+	}
+	return row
+}
+
+// all keys of rows, sorted
+func keysOf(rows []map[string]string) []string {
+	var keys []string
+	for _, row := range rows {
+		for key := range row {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// the text of one table cell
+func cell(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	case reflect.Slice, reflect.Array:
+		if strs, ok := v.Interface().([]string); ok {
+			return strings.Join(strs, ", ")
+		}
+		if v.Type().Elem().Implements(reflect.TypeOf((*fmt.Stringer)(nil)).Elem()) {
+			var parts []string
+			for i := 0; i < v.Len(); i++ {
+				parts = append(parts, v.Index(i).Interface().(fmt.Stringer).String())
+			}
+			return strings.Join(parts, "; ")
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(fwew.Word{}.Affixes) {
+			return affixText(v.Interface())
+		}
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	j, _ := json.Marshal(v.Interface())
+	return string(j)
+}
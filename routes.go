@@ -0,0 +1,275 @@
+package main
+
+import (
+	"net/http"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// param describes a path or query parameter of a route.
+// Path parameters that are not listed are documented as plain strings.
+type param struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // "string", "integer" or "boolean"
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+// route describes one endpoint. The router, the index at /api/
+// and the OpenAPI document at /api/openapi.json are all built from routes.
+type route struct {
+	Name     string
+	Path     string
+	Methods  []string // GET if empty
+	Handler  http.HandlerFunc
+	Summary  string
+	Params   []param
+	Body     any    // example value of the request body type, if any
+	Response any    // example value of the response type
+	Produces string // media type of the response, if it is not rendered (see render)
+	Cache    bool   // responses only depend on the request and the dictionary, see cacheable
+	Heavy    bool   // expensive to answer, limited by HeavyConcurrency, see heavy
+}
+
+// language codes understood by fwew-lib
+var languages = []string{"de", "en", "es", "et", "fr", "hu", "it", "ko", "nl", "pl", "pt", "ru", "sv", "tr", "uk"}
+
+// parameters every route accepts, see render
+var outputParams = []param{formatParam, columnsParam}
+
+// parameters shared between routes
+var (
+	navParam       = param{Name: "nav", In: "path", Type: "string", Description: "Na'vi word or words, plain or affixed"}
+	localParam     = param{Name: "local", In: "path", Type: "string", Description: "word in the given language"}
+	langParam      = param{Name: "lang", In: "path", Type: "string", Description: "language code", Enum: languages}
+	verbParam      = param{Name: "verb", In: "path", Type: "string", Description: "Na'vi verb, as in the dictionary"}
+	strictParam    = param{Name: "strict", In: "path", Type: "string", Description: "strict matching", Enum: []string{"true", "false"}}
+	argsParam      = param{Name: "args", In: "path", Type: "string", Description: "\"what cond spec\" filter string, see /list-help/{lang}"}
+	checkParam     = param{Name: "c", In: "path", Type: "string", Description: "check digraphs", Enum: []string{"true", "maybe", "false"}}
+	countParam     = param{Name: "n", In: "path", Type: "integer", Description: "number of results"}
+	dialectParam   = param{Name: "dialect", In: "path", Type: "string", Description: "dialect of the generated names; anything else means interdialect", Enum: []string{"forest", "reef", "interdialect"}}
+	syllablesParam = param{Name: "s", In: "path", Type: "integer", Description: "syllable count (0 to 4, 0 is random)"}
+	offsetParam    = param{Name: "offset", In: "query", Type: "integer", Description: "number of words to skip"}
+	limitParam     = param{Name: "limit", In: "query", Type: "integer", Description: "maximum number of words to return (default all)"}
+	fieldsParam    = param{Name: "fields", In: "query", Type: "string", Description: "comma-separated Word fields to return, e.g. Navi,IPA,EN"}
+	sortParam      = param{Name: "sort", In: "query", Type: "string", Description: "sort order (default alpha, or none for \"words first\" and \"words last\")", Enum: listSorts}
+	noResultsParam = param{Name: "noresults", In: "query", Type: "string", Description: "answer an empty search with a 404 error (default) or with 200 and an empty array", Enum: []string{"error", "empty"}}
+	deckParam      = param{Name: "deck", In: "query", Type: "string", Description: "name of the Anki deck to import into (default Na'vi)"}
+	formatParam    = param{Name: "format", In: "query", Type: "string", Description: "output format; overrides the Accept header", Enum: outputFormats}
+	rankParam      = param{Name: "rank", In: "query", Type: "boolean", Description: "score the results of every searched word and sort them best first, with the reasons for each score (default false)"}
+	explainParam   = param{Name: "explain", In: "query", Type: "boolean", Description: "explain the affixes of every word: function, description in lang (en where it has no translation) and dictionary entry (default false)"}
+	explainLang    = param{Name: "lang", In: "query", Type: "string", Description: "language of affix explanations (default en); descriptions without a translation are in en, see the lang of each explanation", Enum: languages}
+	columnsParam   = param{Name: "columns", In: "query", Type: "string", Description: "comma-separated columns of CSV, TSV and text output"}
+	withSeedParam  = param{Name: "withseed", In: "query", Type: "boolean", Description: "return {\"seed\": ..., \"words\": [...]} or {\"seed\": ..., \"names\": \"...\"} instead of the words or names alone (default false)"}
+	seedParam      = param{Name: "seed", In: "query", Type: "integer", Description: "seed of the random choices; the same seed and dictionary build give the same output (default a new seed); the seed used is returned in X-Seed"}
+)
+
+// conjugation parameters of the pre-first and second infix positions
+var slotParams = []param{
+	{Name: "evidential", In: "query", Type: "string", Description: "evidential (default none)", Enum: evidentials},
+	{Name: "affect", In: "query", Type: "string", Description: "speaker's attitude (default none)", Enum: affects},
+	{Name: "formal", In: "query", Type: "boolean", Description: "ceremonial speech (default false)"},
+	{Name: "causative", In: "query", Type: "boolean", Description: "causative (default false)"},
+	{Name: "reflexive", In: "query", Type: "boolean", Description: "reflexive (default false)"},
+}
+
+// noun modes accepted by getNameAlu
+var nounModes = []string{"something", "normal noun", "verb-er"}
+
+// adjective modes accepted by getNameAlu
+var adjectiveModes = []string{"something", "any", "none", "normal adjective", "genitive noun", "origin noun", "participle verb", "active participle verb", "passive participle verb"}
+
+// full name endings accepted by getFullNames; anything else picks one at random
+var nameEndings = []string{"'itan", "'ite", "'itu", "random"}
+
+// example response values for the OpenAPI document
+var (
+	words2D = [][]fwew.Word{}
+	words1D = []fwew.Word{}
+	// search results, which carry a rank and explanations when asked for
+	results2D = [][]annotatedWord{}
+	results1D = []annotatedWord{}
+)
+
+// The routes, in the order the router tries them.
+// Filled in init because some handlers read routes themselves.
+var routes []route
+
+func init() {
+	routes = []route{
+		{Path: "/api/", Handler: getEndpoints, Summary: "Fwew API Index", Response: map[string]string{}},
+		{Path: "/api/openapi.json", Handler: getOpenAPI, Summary: "OpenAPI 3 description of this API", Response: map[string]any{}},
+		{Path: "/api/anki/list/{lang}", Handler: ankiList, Summary: "Export all Words as Anki notes (tab-separated text import)",
+			Params: []param{langParam, deckParam}, Response: "", Produces: ankiContentType, Cache: true, Heavy: true},
+		{Path: "/api/anki/list/{lang}/{args}", Handler: ankiList, Summary: "Export Words with attribute filtering as Anki notes (tab-separated text import)",
+			Params: []param{langParam, argsParam, deckParam}, Response: "", Produces: ankiContentType, Cache: true, Heavy: true},
+		{Path: "/api/anki/random/{lang}/{n}/{c}", Handler: ankiRandom, Summary: "Export random Words as Anki notes (tab-separated text import)",
+			Params: []param{langParam, countParam, checkParam, deckParam, seedParam}, Response: "", Produces: ankiContentType, Heavy: true},
+		{Path: "/api/anki/random/{lang}/{n}/{c}/{args}", Handler: ankiRandom, Summary: "Export random Words with attribute filtering as Anki notes (tab-separated text import)",
+			Params: []param{langParam, countParam, checkParam, argsParam, deckParam, seedParam}, Response: "", Produces: ankiContentType, Heavy: true},
+		{Path: "/api/autocomplete/{lang}/{prefix}", Handler: getAutocomplete, Summary: "Complete the beginning of a Na'vi word or of a word in the given language",
+			Params: []param{langParam,
+				{Name: "prefix", In: "path", Type: "string", Description: "beginning of the word; apostrophes and diacritics are ignored"},
+				{Name: "limit", In: "query", Type: "integer", Description: "number of completions (default 10, at most 50)"}},
+			Response: []completion{}, Cache: true},
+		{Path: "/api/batch/fwew", Methods: []string{http.MethodPost}, Handler: batchSearchWord,
+			Summary: "Search many Na'vi inputs at once (POST a JSON array of queries, returns results keyed by query)",
+			Body:    []batchQuery{}, Response: map[string]batchResult{}, Heavy: true},
+		{Path: "/api/calc", Handler: getCalculation, Summary: "Evaluate an expression of Na'vi number words with a worked solution",
+			Params:   []param{{Name: "expr", In: "query", Type: "string", Description: "Na'vi number words joined by + - * / and parentheses, e.g. mevol + pxey", Required: true}},
+			Response: calculation{}, Cache: true},
+		{Path: "/api/conjugate/{verb}", Handler: getConjugation, Summary: "Conjugate a Na'vi verb by putting infixes into its infix positions",
+			Params: append([]param{verbParam,
+				{Name: "tense", In: "query", Type: "string", Description: "tense (default present)", Enum: tenses},
+				{Name: "aspect", In: "query", Type: "string", Description: "aspect (default none)", Enum: aspects},
+				{Name: "mood", In: "query", Type: "string", Description: "mood (default indicative)", Enum: moods},
+				{Name: "intent", In: "query", Type: "boolean", Description: "intended future, with tense=future or near-future (default false)"},
+				{Name: "participle", In: "query", Type: "string", Description: "participle instead of tense, aspect and mood (default none)", Enum: participles}},
+				slotParams...),
+			Response: conjugation{}, Cache: true},
+		{Path: "/api/conjugate/{verb}/paradigm", Handler: getParadigm, Summary: "Conjugate a Na'vi verb for every tense, aspect, mood and participle",
+			Params:   append([]param{verbParam}, slotParams...),
+			Response: []conjugation{}, Cache: true},
+		{Path: "/api/decline/{noun}", Handler: getDeclension, Summary: "Decline a Na'vi noun or pronoun for every case and number, checking each form with the analyser",
+			Params:   []param{{Name: "noun", In: "path", Type: "string", Description: "Na'vi noun or pronoun, as in the dictionary"}, noResultsParam},
+			Response: declension{}, Cache: true, Heavy: true},
+		{Path: "/api/fwew/{nav}", Handler: searchWord,
+			Summary: "Search Word Na'vi -> Local (returns 2-Dimensional Word array)",
+			Params:  []param{navParam, rankParam, explainParam, explainLang, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/fwew-reef/{strict}/{nav}", Handler: searchWordReef,
+			Summary: "Search Word Reef Na'vi -> Local (returns 2-Dimensional Word array)",
+			Params:  []param{strictParam, navParam, rankParam, explainParam, explainLang, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/fwew-strict/{nav}", Handler: searchWordStrict,
+			Summary: "Search Word Na'vi -> Local with strict matching (returns 2-Dimensional Word array)",
+			Params:  []param{navParam, rankParam, explainParam, explainLang, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/fwew/r/{lang}/{local}", Handler: searchWordReverse,
+			Summary: "Search Word Local -> Na'vi (returns 2-Dimensional Word array)",
+			Params:  []param{langParam, localParam, rankParam, explainParam, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/fwew-1d/{nav}", Handler: searchWord1d,
+			Summary: "Search Word Na'vi -> Local (returns 1-Dimensional Word array)",
+			Params:  []param{navParam, rankParam, explainParam, explainLang, noResultsParam}, Response: results1D, Cache: true},
+		{Path: "/api/fwew-1d/r/{lang}/{local}", Handler: searchWordReverse1d,
+			Summary: "Search Word Local -> Na'vi (returns 1-Dimensional Word array)",
+			Params:  []param{langParam, localParam, rankParam, explainParam, noResultsParam}, Response: results1D, Cache: true},
+		{Path: "/api/fwew-simple/{strict}/{nav}", Handler: simpleSearchWord,
+			Summary: "Search Na'vi -> Local without checking affixes (returns 2-Dimensional Word array)",
+			Params:  []param{strictParam, navParam, rankParam, explainParam, explainLang, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/gloss/{lang}", Methods: []string{http.MethodPost}, Handler: glossSentence,
+			Summary: "Interlinear gloss of a Na'vi sentence (POST the sentence as plain text or as {\"text\": ...})",
+			Params:  []param{langParam}, Body: glossRequest{}, Response: glossResult{}, Heavy: true},
+		{Path: "/api/homonyms", Handler: getHomonyms, Summary: "List Na'vi Homonyms", Response: words2D, Cache: true},
+		{Path: "/api/lenite/{word}", Handler: getLenition, Summary: "Lenite a Na'vi word and see which rule applied",
+			Params: []param{{Name: "word", In: "path", Type: "string", Description: "Na'vi word"}}, Response: lenition{}, Cache: true},
+		{Path: "/api/lenite/r/{word}", Handler: getLenitionSources, Summary: "List the possible unlenited sources of a lenited Na'vi word and which are in the dictionary",
+			Params: []param{{Name: "word", In: "path", Type: "string", Description: "lenited Na'vi word"}}, Response: []lenitionSource{}, Cache: true},
+		{Path: "/api/lenition", Handler: getLenitionTable, Summary: "Na'vi Lenition Table",
+			Params: []param{{Name: "lang", In: "query", Type: "string", Description: "language of the note on ' (default en)", Enum: languages}}, Response: map[string]string{}, Cache: true},
+		{Path: "/api/list", Handler: listWords, Summary: "List all Words (returns 1-Dimensional Word array)",
+			Params: []param{offsetParam, limitParam, fieldsParam, sortParam, noResultsParam}, Response: words1D, Cache: true, Heavy: true},
+		{Path: "/api/list/{args}", Handler: listWords, Summary: "List Words with attribute filtering",
+			Params: []param{argsParam, offsetParam, limitParam, fieldsParam, sortParam, noResultsParam}, Response: words1D, Cache: true, Heavy: true},
+		{Path: "/api/list2/{c}/{args}", Handler: listWords2, Summary: "List Words with attribute filtering and check-digraphs options",
+			Params: []param{checkParam, argsParam, offsetParam, limitParam, fieldsParam, sortParam, noResultsParam}, Response: words1D, Cache: true, Heavy: true},
+		{Path: "/api/list-help/{lang}", Handler: listWordsHelp, Summary: "Show all the commands that can be put into list or random",
+			Params: []param{langParam}, Response: "", Cache: true},
+		{Path: "/api/multi-ipa", Handler: getMultiIPA, Summary: "List Words with multiple IPA values (alternative pronunciation)", Response: words2D, Cache: true},
+		{Path: "/api/multiwordwords", Handler: getMultiwordWords, Summary: "List Words that have two or more parts separated by a space", Response: map[string][][]string{}, Cache: true},
+		{Path: "/api/name/alu/{n}/{s}/{nm}/{am}/{dialect}", Handler: getNameAlu, Summary: "Generate title style name(s)",
+			Params: []param{countParam, syllablesParam,
+				{Name: "nm", In: "path", Type: "string", Description: "noun mode", Enum: nounModes},
+				{Name: "am", In: "path", Type: "string", Description: "adjective mode", Enum: adjectiveModes},
+				dialectParam, seedParam, withSeedParam},
+			Response: "", Heavy: true},
+		{Path: "/api/name/full/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}", Handler: getFullNames, Summary: "Generate Na'vi names in full canonical format",
+			Params:   fullNameParams(),
+			Response: "", Heavy: true},
+		{Path: "/api/name/full/d/{ending}/{n}/{s1}/{s2}/{s3}/{dialect}", Handler: getFullNamesDiscord, Summary: "Generate Na'vi names in full canonical format.  Stop before Discord's 2000 character limit",
+			Params:   fullNameParams(),
+			Response: "", Heavy: true},
+		{Path: "/api/name/single/{n}/{s}/{dialect}", Handler: getSingleNames, Summary: "Generate single Na'vi names",
+			Params: []param{countParam, syllablesParam, dialectParam, seedParam, withSeedParam}, Response: "", Heavy: true},
+		{Path: "/api/number/{word}", Handler: searchNumber, Summary: "Search a Na'vi number word to see the decimal and octal numeral forms",
+			Params: []param{{Name: "word", In: "path", Type: "string", Description: "Na'vi number word, e.g. mevolaw"}}, Response: number{}, Cache: true},
+		{Path: "/api/number/r/{num}", Handler: searchNumberReverse, Summary: "Search an integer number between 0 and 32767 to see the Na'vi word and octal numeral forms",
+			Params: []param{{Name: "num", In: "path", Type: "string", Description: "integer between 0 and 32767, decimal or octal (0o17, 017 or \"17 octal\")"}}, Response: number{}, Cache: true},
+		{Path: "/api/numbers", Handler: getNumbers, Summary: "List the Na'vi numbers of a range of integers, for number charts",
+			Params: []param{
+				{Name: "from", In: "query", Type: "string", Description: "first integer, decimal or octal (0o17 or \"17 octal\")", Required: true},
+				{Name: "to", In: "query", Type: "string", Description: "last integer, at most 511 after from", Required: true},
+			}, Response: []number{}, Cache: true},
+		{Path: "/api/oddballs", Handler: getOddballs, Summary: "List Words that are canon but contradict Na'vi syllable rules", Response: words2D, Cache: true},
+		{Path: "/api/phonemedistros", Handler: getPhonemeDistrosEN, Summary: "Get Phoneme Distribution data in English", Response: [][][]string{}, Cache: true},
+		{Path: "/api/phonemedistros/{lang}", Handler: getPhonemeDistros, Summary: "Get Phoneme Distribution data",
+			Params: []param{langParam}, Response: [][][]string{}, Cache: true},
+		{Path: "/api/random/{n}", Handler: getRandomWords, Summary: "Get random Words",
+			Params: []param{countParam, noResultsParam, seedParam, withSeedParam}, Response: words1D, Heavy: true},
+		{Path: "/api/random/{n}/{args}", Handler: getRandomWords, Summary: "Get random Words with attribute filtering",
+			Params: []param{countParam, argsParam, noResultsParam, seedParam, withSeedParam}, Response: words1D, Heavy: true},
+		{Path: "/api/random2/{n}/{c}", Handler: getRandomWords2, Summary: "Get random Words with check-digraphs options",
+			Params: []param{countParam, checkParam, noResultsParam, seedParam, withSeedParam}, Response: words1D, Heavy: true},
+		{Path: "/api/random2/{n}/{c}/{args}", Handler: getRandomWords2, Summary: "Get random Words with attribute filtering and check-digraphs options",
+			Params: []param{countParam, checkParam, argsParam, noResultsParam, seedParam, withSeedParam}, Response: words1D, Heavy: true},
+		{Path: "/api/reef/{i}", Handler: getReefFromIpa, Summary: "Get Reef Na'vi syllables and IPA by Forest Na'vi IPA",
+			Params: []param{{Name: "i", In: "path", Type: "string", Description: "Forest Na'vi IPA"}}, Response: []string{}, Cache: true},
+		{Path: "/api/search/{lang}/{words}", Handler: searchBidirectional, Summary: "Search Na'vi <-> Local",
+			Params: []param{langParam, {Name: "words", In: "path", Type: "string", Description: "Na'vi or local words"}, rankParam, explainParam, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/search-reef/{lang}/{words}", Handler: searchBidirectionalReef, Summary: "Search Reef Na'vi <-> Local",
+			Params: []param{langParam, {Name: "words", In: "path", Type: "string", Description: "Na'vi or local words"}, rankParam, explainParam, noResultsParam}, Response: results2D, Cache: true},
+		{Path: "/api/suggest/{nav}", Handler: getSuggestions, Summary: "Suggest dictionary words close to a misspelled Na'vi word",
+			Params: []param{navParam,
+				{Name: "n", In: "query", Type: "integer", Description: "number of suggestions (default 5, at most 50)"},
+				{Name: "max", In: "query", Type: "integer", Description: "greatest edit distance (default 1 to 3, depending on the length of nav)"},
+				noResultsParam},
+			Response: []suggestion{}, Cache: true},
+		{Path: "/api/total-words", Handler: getDictLenSimple, Summary: "Get the number of Words in the dictionary as a number", Response: 0, Cache: true},
+		{Path: "/api/total-words/{lang}", Handler: getDictLen, Summary: "Get the number of Words in the dictionary as a complete sentence in the specified language",
+			Params: []param{langParam}, Response: "", Cache: true},
+		{Path: "/api/v2/translate", Handler: translateV2, Summary: "Search Word Na'vi <-> Local with all options in the query string",
+			Params: []param{
+				{Name: "q", In: "query", Type: "string", Description: "text to translate", Required: true},
+				{Name: "dir", In: "query", Type: "string", Description: "Na'vi -> local (nav) or local -> Na'vi (local)", Enum: []string{"nav", "local"}},
+				{Name: "lang", In: "query", Type: "string", Description: "language code of q when dir=local, and of affix explanations (falling back to en, see the lang of each explanation)", Enum: languages},
+				{Name: "affixes", In: "query", Type: "boolean", Description: "check for affixed forms (default true)"},
+				{Name: "strict", In: "query", Type: "boolean", Description: "strict matching (default false)"},
+				{Name: "dialect", In: "query", Type: "string", Description: "dialect of q", Enum: []string{"forest", "reef"}},
+				{Name: "shape", In: "query", Type: "string", Description: "2-Dimensional or 1-Dimensional Word array", Enum: []string{"2d", "1d"}},
+				rankParam,
+				explainParam,
+				noResultsParam,
+			},
+			Response: results2D, Cache: true},
+		{Name: "update", Path: "/api/update", Methods: []string{http.MethodPost}, Handler: update,
+			Summary: "Reload the dictionary cache (requires admin credentials)", Response: updateResult{}},
+		{Path: "/api/valid/{i}", Handler: getValidityEN, Summary: "Check if a given word string (e.g., name, loan word, etc.) follows all Na'vi syllable rules.  Return results in English.",
+			Params: []param{{Name: "i", In: "path", Type: "string", Description: "word to check"}}, Response: "", Cache: true},
+		{Path: "/api/valid/{lang}/{i}", Handler: getValidity, Summary: "Check if a given word string (e.g., name, loan word, etc.) follows all Na'vi syllable rules.  Return results in specified language",
+			Params: []param{langParam, {Name: "i", In: "path", Type: "string", Description: "word to check"}}, Response: "", Cache: true},
+		{Path: "/api/valid/d/{lang}/{i}", Handler: getValidityDiscord, Summary: "Check if a given word string follows all Na'vi syllable rules.  Return results in specified language under Discord's 2000 character limit.",
+			Params: []param{langParam, {Name: "i", In: "path", Type: "string", Description: "word to check"}}, Response: "", Cache: true},
+		{Path: "/api/version", Handler: getVersion, Summary: "Version information", Response: Version{}},
+	}
+}
+
+// parameters of the full name generators
+func fullNameParams() []param {
+	return []param{
+		{Name: "ending", In: "path", Type: "string", Description: "name ending; anything else picks one at random", Enum: nameEndings},
+		countParam,
+		{Name: "s1", In: "path", Type: "integer", Description: "syllable count of the first name (0 to 4, 0 is random)"},
+		{Name: "s2", In: "path", Type: "integer", Description: "syllable count of the family name (0 to 4, 0 is random)"},
+		{Name: "s3", In: "path", Type: "integer", Description: "syllable count of the parent's name (0 to 4, 0 is random)"},
+		dialectParam,
+		seedParam,
+		withSeedParam,
+	}
+}
+
+// methods of a route
+func (rt route) methods() []string {
+	if len(rt.Methods) == 0 {
+		return []string{http.MethodGet}
+	}
+	return rt.Methods
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// fwew-lib's name generators draw from the global math/rand source and take no
+// generator of their own, so seeding them means seeding that source. randomMu makes
+// seeded generators take turns, and nothing else may use the global source: fwew-api
+// only imports math/rand here, and does not call fwew.Random, the one other function
+// of fwew-lib that draws from it (see TestGlobalRandOnlyInSeed).
+// rand.Seed only has an effect with randseednop=0, see the go:debug line of main.go.
+// Random words are drawn with a generator of their own, see pickWords.
+var randomMu sync.Mutex
+
+// seeds made up for requests without ?seed= stay below 2^53, so that
+// JavaScript clients can send them back without losing precision
+const maxNewSeed = 1 << 53
+
+// seededNames are generated names with the seed that generated them, sent with ?withseed=true.
+type seededNames struct {
+	Seed  int64  `json:"seed"`
+	Names string `json:"names"`
+}
+
+// CSV, TSV and text output have one name per line, as without the seed
+func (s seededNames) payload() any {
+	return s.Names
+}
+
+// seededWords are random words with the seed that chose them, sent with ?withseed=true.
+type seededWords struct {
+	Seed  int64       `json:"seed"`
+	Words []fwew.Word `json:"words"`
+}
+
+// CSV, TSV and text output list the words, as without the seed
+func (s seededWords) payload() any {
+	return s.Words
+}
+
+// The seed asked for in ?seed=, or a new one, which is also put in the X-Seed header.
+// Writes the error and returns false if ?seed= is not an integer or ?withseed= not a boolean.
+func requestSeed(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	query := r.URL.Query()
+	if _, e := boolParam(query.Get("withseed"), "withseed", false); e != nil {
+		writeError(w, e)
+		return 0, false
+	}
+	seed := time.Now().UnixNano() % maxNewSeed
+	if value := query.Get("seed"); value != "" {
+		var err error
+		seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			writeError(w, errInvalidParam("seed", value, "a decimal integer", "invalidDecimalError"))
+			return 0, false
+		}
+	}
+	w.Header().Set("X-Seed", strconv.FormatInt(seed, 10))
+	return seed, true
+}
+
+// Render random words or names as they are, or together with their seed if ?withseed=true
+func renderSeeded(w http.ResponseWriter, r *http.Request, seed int64, v any) {
+	if withSeed, _ := boolParam(r.URL.Query().Get("withseed"), "withseed", false); withSeed {
+		switch v := v.(type) {
+		case string:
+			render(w, r, seededNames{Seed: seed, Names: v})
+			return
+		case []fwew.Word:
+			render(w, r, seededWords{Seed: seed, Words: v})
+			return
+		}
+	}
+	render(w, r, v)
+}
+
+// Write the names made by generate from ?seed=, or from a new seed
+func writeSeededNames(w http.ResponseWriter, r *http.Request, generate func() string) {
+	seed, ok := requestSeed(w, r)
+	if !ok {
+		return
+	}
+	renderSeeded(w, r, seed, generateNames(seed, generate))
+}
+
+// The names made by generate with the global random source seeded by seed.
+// The same seed and dictionary build give the same names.
+func generateNames(seed int64, generate func() string) string {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	rand.Seed(seed)
+	return generate()
+}
+
+// Like fwew.Random, drawing from a generator seeded by seed instead of the global source:
+// n words picked at random from the words matching args, or a random number of them if n <= 0
+func randomWords(seed int64, n int, args []string, checkDigraphs uint8) ([]fwew.Word, error) {
+	words, err := fwew.List(args, checkDigraphs)
+	if err != nil || len(words) == 0 {
+		return nil, err
+	}
+	return pickWords(words, n, seed), nil
+}
+
+// n of words picked at random with a generator seeded by seed, or a random number of them if n <= 0
+func pickWords(words []fwew.Word, n int, seed int64) []fwew.Word {
+	rng := rand.New(rand.NewSource(seed))
+	if n <= 0 {
+		n = rng.Intn(len(words)) + 1
+	}
+	if n >= len(words) {
+		return words
+	}
+	picked := make([]fwew.Word, 0, n)
+	for _, i := range rng.Perm(len(words))[:n] {
+		picked = append(picked, words[i])
+	}
+	return picked
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// the name generators of the name routes
+var nameGenerators = map[string]func() string{
+	"single": func() string { return fwew.SingleNames(5, 0, 2) },
+	"full":   func() string { return fwew.FullNames("'itan", 5, 0, [3]int{2, 2, 2}, false) },
+	"alu":    func() string { return fwew.NameAlu(5, 0, 2, 1, 1) }, // no adjective: testdata has none
+}
+
+func TestGenerateNamesRepeatable(t *testing.T) {
+	for name, generate := range nameGenerators {
+		first := generateNames(42, generate)
+		if first == "" {
+			t.Errorf("%s: no names", name)
+		}
+		if again := generateNames(42, generate); again != first {
+			t.Errorf("%s: seed 42 gave %q, then %q", name, first, again)
+		}
+		if other := generateNames(43, generate); other == first {
+			t.Errorf("%s: seeds 42 and 43 both gave %q", name, first)
+		}
+	}
+}
+
+// seeded requests running at the same time do not take numbers out of each other's sequence
+func TestGenerateNamesConcurrent(t *testing.T) {
+	generate := nameGenerators["single"]
+	want := map[int64]string{}
+	for seed := int64(1); seed <= 4; seed++ {
+		want[seed] = generateNames(seed, generate)
+	}
+
+	var wg sync.WaitGroup
+	for seed := range want {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if got := generateNames(seed, generate); got != want[seed] {
+					t.Errorf("seed %d gave %q, want %q", seed, got, want[seed])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPickWordsRepeatable(t *testing.T) {
+	var words []fwew.Word
+	for i := range 20 {
+		words = append(words, fwew.Word{ID: strconv.Itoa(i)})
+	}
+	ids := func(picked []fwew.Word) []string {
+		var ids []string
+		for _, word := range picked {
+			ids = append(ids, word.ID)
+		}
+		return ids
+	}
+
+	for _, n := range []int{0, 1, 5, 19} {
+		first := ids(pickWords(words, n, 7))
+		if again := ids(pickWords(words, n, 7)); !slices.Equal(again, first) {
+			t.Errorf("pickWords(%d, seed 7) gave %q, then %q", n, first, again)
+		}
+		if n > 0 && len(first) != n {
+			t.Errorf("pickWords(%d) picked %d words", n, len(first))
+		}
+	}
+	if got := pickWords(words, 30, 7); len(got) != len(words) {
+		t.Errorf("pickWords(30) of 20 words picked %d", len(got))
+	}
+}
+
+// Only seed.go may use the global math/rand source, or seeded names would not repeat
+func TestGlobalRandOnlyInSeed(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "*.go")) // TestMain runs in testdata
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || filepath.Base(file) == "seed.go" {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, src, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range f.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); path == "math/rand" {
+				t.Errorf("%s imports math/rand", file)
+			}
+		}
+		if strings.Contains(string(src), "fwew.Random(") {
+			t.Errorf("%s calls fwew.Random, which draws from the global source", file)
+		}
+	}
+}
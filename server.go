@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// duration is a time.Duration read from config.json as a string such as "15s" or "2m"
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// fill in defaults for the server settings of config
+func defaultServerConfig() {
+	if config.ReadHeaderTimeout.Duration <= 0 {
+		config.ReadHeaderTimeout.Duration = 5 * time.Second
+	}
+	if config.ReadTimeout.Duration <= 0 {
+		config.ReadTimeout.Duration = 15 * time.Second
+	}
+	if config.WriteTimeout.Duration <= 0 {
+		config.WriteTimeout.Duration = 60 * time.Second
+	}
+	if config.IdleTimeout.Duration <= 0 {
+		config.IdleTimeout.Duration = 120 * time.Second
+	}
+	if config.MaxHeaderBytes <= 0 {
+		config.MaxHeaderBytes = 64 << 10
+	}
+	if config.ShutdownGracePeriod.Duration <= 0 {
+		config.ShutdownGracePeriod.Duration = 30 * time.Second
+	}
+}
+
+// The TLS configuration asked for by config, or nil to serve plain HTTP.
+// TLSCertFile and TLSKeyFile take precedence over TLSCertDir.
+func tlsConfig() *tls.Config {
+	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+		// the certificate is loaded by ListenAndServeTLS
+		return &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if config.TLSCertDir != "" {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(config.TLSCertDir),
+			HostPolicy: autocert.HostWhitelist(config.TLSHosts...),
+		}
+		c := m.TLSConfig()
+		c.MinVersion = tls.VersionTLS12
+		return c
+	}
+
+	return nil
+}
+
+// Serve handler until SIGINT or SIGTERM, then stop accepting connections
+// and give in-flight requests up to ShutdownGracePeriod to finish.
+func serve(handler http.Handler) {
+	server := &http.Server{
+		Addr:              ":" + config.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout.Duration,
+		ReadTimeout:       config.ReadTimeout.Duration,
+		WriteTimeout:      config.WriteTimeout.Duration,
+		IdleTimeout:       config.IdleTimeout.Duration,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		TLSConfig:         tlsConfig(),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			log.Printf("listening on %s (TLS)", server.Addr)
+			errs <- server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			log.Printf("listening on %s", server.Addr)
+			errs <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("shutting down, waiting up to %s for in-flight requests", config.ShutdownGracePeriod)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownGracePeriod.Duration)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+	"github.com/gorilla/mux"
+	"golang.org/x/text/unicode/norm"
+)
+
+// letters of more than one character, compared as one unit
+var naviDigraphs = []string{"kx", "px", "tx", "ts", "ng", "ll", "rr"}
+
+// suggestion is a headword close to a misspelled search.
+type suggestion struct {
+	Navi     string `json:"navi"`
+	Distance int    `json:"distance"`
+}
+
+// suggestEntry is one headword of the suggestion index.
+type suggestEntry struct {
+	navi  string
+	units []string
+}
+
+// all distinct headwords, rebuilt with every dictionary load (see dictionaryLoaded)
+var suggestIndex []suggestEntry
+
+// rebuild suggestIndex from the current dictionary
+func buildSuggestIndex() {
+	words, err := fwew.List(nil, 0)
+	if err != nil {
+		log.Printf("suggestion index: %v", err)
+		return
+	}
+
+	seen := map[string]bool{}
+	index := make([]suggestEntry, 0, len(words))
+	for _, word := range words {
+		key := strings.ToLower(word.Navi)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		index = append(index, suggestEntry{navi: word.Navi, units: naviUnits(word.Navi)})
+	}
+	suggestIndex = index
+}
+
+// Split Na'vi text into letters, with digraphs as single units.
+// Case, the apostrophe variant and composed or decomposed ä and ì do not matter.
+func naviUnits(s string) []string {
+	s = norm.NFC.String(strings.ToLower(strings.TrimSpace(s)))
+	s = strings.NewReplacer("’", "'", "‘", "'").Replace(s)
+
+	var units []string
+	for len(s) > 0 {
+		unit := ""
+		for _, digraph := range naviDigraphs {
+			if strings.HasPrefix(s, digraph) {
+				unit = digraph
+				break
+			}
+		}
+		if unit == "" {
+			for _, c := range s {
+				unit = string(c)
+				break
+			}
+		}
+		units = append(units, unit)
+		s = s[len(unit):]
+	}
+	return units
+}
+
+// Damerau-Levenshtein distance (optimal string alignment) between two unit sequences.
+// Gives up and returns limit+1 once the distance is known to exceed limit.
+func unitDistance(a, b []string, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// the default greatest distance of suggestions for a query of n letters
+func defaultMaxDistance(n int) int {
+	switch {
+	case n <= 3:
+		return 1
+	case n <= 6:
+		return 2
+	}
+	return 3
+}
+
+// The count headwords closest to query, at most maxDistance edits away
+// (defaultMaxDistance if negative), closest first
+func suggest(query string, count int, maxDistance int) []suggestion {
+	units := naviUnits(query)
+	if len(units) == 0 {
+		return nil
+	}
+	if maxDistance < 0 {
+		maxDistance = defaultMaxDistance(len(units))
+	}
+
+	var found []suggestion
+	for _, entry := range suggestIndex {
+		if d := unitDistance(units, entry.units, maxDistance); d <= maxDistance {
+			found = append(found, suggestion{Navi: entry.navi, Distance: d})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Distance != found[j].Distance {
+			return found[i].Distance < found[j].Distance
+		}
+		return fwew.AlphabetizeHelper(found[i].Navi, found[j].Navi)
+	})
+	if len(found) > count {
+		found = found[:count]
+	}
+	return found
+}
+
+// Suggest headwords for a misspelled Na'vi word:
+//
+//	n    number of suggestions (default 5, at most 50)
+//	max  greatest edit distance (default 1 to 3, depending on the length of nav)
+func getSuggestions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	count, e := nonNegativeParam(query, "n")
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	if count == 0 {
+		count = 5
+	}
+	count = min(count, 50)
+
+	maxDistance := -1
+	if query.Get("max") != "" {
+		if maxDistance, e = nonNegativeParam(query, "max"); e != nil {
+			writeError(w, e)
+			return
+		}
+	}
+
+	found := suggest(mux.Vars(r)["nav"], count, maxDistance)
+	if len(found) == 0 {
+		writeNoResults(w, r)
+		return
+	}
+
+	setResultCount(r, len(found))
+	render(w, r, found)
+}
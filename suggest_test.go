@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNaviUnits(t *testing.T) {
+	tests := map[string][]string{
+		"skxawng":   {"s", "kx", "a", "w", "ng"},
+		"Tsmukan":   {"ts", "m", "u", "k", "a", "n"},
+		"’rrpxì":    {"'", "rr", "px", "ì"},
+		"a\u0308ll": {"ä", "ll"}, // decomposed ä
+		"  txon ":   {"tx", "o", "n"},
+		"":          nil,
+	}
+	for s, want := range tests {
+		if got := naviUnits(s); !slices.Equal(got, want) {
+			t.Errorf("naviUnits(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestUnitDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		limit    int
+		distance int
+	}{
+		{"skxawng", "skxawng", 3, 0},
+		{"skawng", "skxawng", 3, 1},  // k for kx is one edit, not an insertion of x
+		{"kaltxi", "kaltxì", 3, 1},   // ì is a letter of its own
+		{"kaltxi", "kaltxì", 0, 1},   // over the limit: limit+1
+		{"tsmukna", "tsmukan", 3, 1}, // transposition
+		{"tute", "ikran", 2, 3},      // gives up early
+		{"oe", "oeng", 3, 1},         // ng is one letter
+		{"'eylan", "eylan", 3, 1},
+		{"ngati", "nati", 3, 1},
+	}
+	for _, tt := range tests {
+		if d := unitDistance(naviUnits(tt.a), naviUnits(tt.b), tt.limit); d != tt.distance {
+			t.Errorf("unitDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, d, tt.distance)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	saved := suggestIndex
+	defer func() { suggestIndex = saved }()
+	suggestIndex = nil
+	for _, navi := range []string{"taron", "tute", "tsmukan", "ikran", "kelku", "'eylan", "eylan"} {
+		suggestIndex = append(suggestIndex, suggestEntry{navi: navi, units: naviUnits(navi)})
+	}
+
+	tests := []struct {
+		query       string
+		count       int
+		maxDistance int
+		want        []suggestion
+	}{
+		{"taronn", 5, -1, []suggestion{{"taron", 1}}},
+		{"eylan", 5, -1, []suggestion{{"eylan", 0}, {"'eylan", 1}}},
+		{"eylan", 1, -1, []suggestion{{"eylan", 0}}},
+		{"tsmukna", 5, 0, nil},
+		{"kelkuu", 5, 1, []suggestion{{"kelku", 1}}},
+		{"", 5, -1, nil},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.query, tt.count, tt.maxDistance); !slices.Equal(got, tt.want) {
+			t.Errorf("suggest(%q, %d, %d) = %v, want %v", tt.query, tt.count, tt.maxDistance, got, tt.want)
+		}
+	}
+}
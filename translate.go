@@ -0,0 +1,258 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	fwew "github.com/fwew/fwew-lib/v5"
+)
+
+// translateOptions describes one translation query.
+// Every /fwew* route and /v2/translate resolve to one of these.
+type translateOptions struct {
+	Query   string
+	Reverse bool   // local -> Na'vi instead of Na'vi -> local
+	Lang    string // language of Query when Reverse
+	Affixes bool   // check for affixed forms
+	Strict  bool
+	Reef    bool
+	Flat    bool // return a 1-dimensional Word array
+}
+
+// run the translation described by opts
+func translate(opts translateOptions) ([][]fwew.Word, error) {
+	if opts.Reverse {
+		return fwew.TranslateToNaviHash(opts.Query, opts.Lang), nil
+	}
+	return fwew.TranslateFromNaviHash(opts.Query, opts.Affixes, opts.Strict, opts.Reef)
+}
+
+// translate and write the results in the requested shape
+func writeTranslation(w http.ResponseWriter, r *http.Request, opts translateOptions) {
+	words, err := translate(opts)
+	if err != nil || len(words) == 0 {
+		writeNoResults(w, r)
+		return
+	}
+	if !opts.Reverse && !foundAny(words) {
+		writeNoResultsError(w, r, errNoResultsSuggest(words))
+		return
+	}
+
+	setResultCount(r, len(words))
+	renderSearch(w, r, words, opts.Flat)
+}
+
+// annotatedWord is a search result with its rank and explanations of its affixes.
+type annotatedWord struct {
+	fwew.Word
+	Rank         *rank              `json:"rank,omitempty"`
+	Explanations []affixExplanation `json:"explanations,omitempty"`
+}
+
+// Write search results, as a 1-dimensional array if flat.
+// With ?rank=true the results of every searched word are scored and sorted best first,
+// with ?explain=true every word gets explanations of its affixes.
+func renderSearch(w http.ResponseWriter, r *http.Request, words [][]fwew.Word, flat bool) {
+	query := r.URL.Query()
+	ranked, e := boolParam(query.Get("rank"), "rank", false)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	explain, e := boolParam(query.Get("explain"), "explain", false)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	if ranked || explain {
+		lang := textLang(r)
+		annotated := make([][]annotatedWord, len(words))
+		for i, group := range words {
+			annotated[i] = unannotated(group)
+			if explain {
+				for j := range annotated[i] {
+					annotated[i][j].Explanations = explainAffixes(group[j], lang)
+				}
+			}
+		}
+		if ranked {
+			rankWords(annotated)
+		}
+		if flat {
+			render(w, r, slices.Concat(annotated...))
+			return
+		}
+		render(w, r, annotated)
+		return
+	}
+
+	if flat {
+		oneDWords := []fwew.Word{}
+		for _, a := range words {
+			oneDWords = append(oneDWords, a...)
+		}
+		render(w, r, oneDWords)
+		return
+	}
+
+	render(w, r, words)
+}
+
+// Whether a Na'vi search found anything. fwew-lib starts the results of
+// every searched word with the word itself, which has no ID.
+func foundAny(words [][]fwew.Word) bool {
+	for _, group := range words {
+		for _, word := range group {
+			if word.ID != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// a no_results error suggesting close headwords for every searched word
+func errNoResultsSuggest(words [][]fwew.Word) *apiError {
+	e := errNoResults()
+	for _, group := range words {
+		if len(group) == 0 {
+			continue
+		}
+		query := group[0].Navi
+		if found := suggest(query, 5, -1); len(found) > 0 {
+			if e.Suggestions == nil {
+				e.Suggestions = map[string][]suggestion{}
+			}
+			e.Suggestions[query] = found
+		}
+	}
+	return e
+}
+
+// The dictionary entries spelled navi, ignoring case, that accept is true for
+// (all of them if accept is nil), and the search result they were taken from
+func headwords(navi string, accept func(fwew.Word) bool) (words []fwew.Word, groups [][]fwew.Word) {
+	groups, err := fwew.TranslateFromNaviHash(navi, false, false, false)
+	if err != nil {
+		return nil, nil
+	}
+	for _, group := range groups {
+		for _, word := range group {
+			if word.ID != "" && strings.EqualFold(word.Navi, navi) && (accept == nil || accept(word)) {
+				words = append(words, word)
+			}
+		}
+	}
+	return words, groups
+}
+
+// The first dictionary entry spelled navi that accept is true for: a no_results error
+// if navi is not in the dictionary, an invalid_parameter error for param if none of
+// its entries is what is expected
+func lookUpHeadword(param string, navi string, expected string, accept func(fwew.Word) bool) (fwew.Word, *apiError) {
+	words, groups := headwords(navi, accept)
+	switch {
+	case len(words) > 0:
+		return words[0], nil
+	case !foundAny(groups):
+		return fwew.Word{}, errNoResultsSuggest(groups)
+	}
+	return fwew.Word{}, errInvalidParam(param, navi, expected, "")
+}
+
+// write an error of lookUpHeadword
+func writeLookUpError(w http.ResponseWriter, r *http.Request, e *apiError) {
+	if e.Code == codeNoResults {
+		writeNoResultsError(w, r, e)
+		return
+	}
+	writeError(w, e)
+}
+
+// Search Na'vi <-> local with all options given in the query string:
+//
+//	q        the text to translate (required)
+//	dir      nav (Na'vi -> local, default) or local (local -> Na'vi)
+//	lang     language code of q when dir=local (default en)
+//	affixes  check for affixed forms (default true)
+//	strict   strict matching (default false)
+//	dialect  forest (default) or reef
+//	shape    2d (default) or 1d
+//	rank     score the results and sort them best first (default false)
+//	explain  explain the affixes of every word (default false)
+func translateV2(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseTranslateQuery(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTranslation(w, r, opts)
+}
+
+// read translateOptions from the query string of a /v2/translate request
+func parseTranslateQuery(r *http.Request) (opts translateOptions, err *apiError) {
+	query := r.URL.Query()
+
+	opts.Query = query.Get("q")
+	if opts.Query == "" {
+		return opts, errMissingParam("q")
+	}
+
+	switch query.Get("dir") {
+	case "", "nav":
+	case "local":
+		opts.Reverse = true
+	default:
+		return opts, errInvalidParam("dir", query.Get("dir"), "nav or local", "")
+	}
+
+	opts.Lang = query.Get("lang")
+	if opts.Lang == "" {
+		opts.Lang = "en"
+	}
+
+	opts.Affixes, err = boolParam(query.Get("affixes"), "affixes", true)
+	if err != nil {
+		return
+	}
+
+	opts.Strict, err = boolParam(query.Get("strict"), "strict", false)
+	if err != nil {
+		return
+	}
+
+	switch query.Get("dialect") {
+	case "", "forest":
+	case "reef":
+		opts.Reef = true
+	default:
+		return opts, errInvalidParam("dialect", query.Get("dialect"), "forest or reef", "")
+	}
+
+	switch query.Get("shape") {
+	case "", "2d":
+	case "1d":
+		opts.Flat = true
+	default:
+		return opts, errInvalidParam("shape", query.Get("shape"), "1d or 2d", "")
+	}
+
+	return opts, nil
+}
+
+// parse an optional boolean query parameter
+func boolParam(value string, name string, fallback bool) (bool, *apiError) {
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback, errInvalidParam(name, value, "true or false", "")
+	}
+	return b, nil
+}